@@ -2,21 +2,37 @@ package cmd
 
 import (
 	"context"
+	_ "expvar" // register /debug/vars
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/sirupsen/logrus"
 	"github.com/whywaita/aguri/pkg/aggregate"
 	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/ingest"
 	"github.com/whywaita/aguri/pkg/reply"
 	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
 )
 
-var configPath = flag.String("config", "config.toml", "config file path")
+const (
+	metricsShutdownTimeout = 5 * time.Second
+)
+
+var (
+	configPath    = flag.String("config", "config.toml", "config file path")
+	metricsListen = flag.String("metrics", "", "listen address of metrics (/debug/vars), disabled if empty")
+	drainTimeout  = flag.Duration("drain", 10*time.Second, "max duration of waiting in-flight messages when shutting down")
+	watchInterval = flag.Duration("watch", 30*time.Second, "interval of checking change of config, disabled if 0 (SIGHUP always reload config)")
+)
 
 // Run is starter of aguri
 func Run(ctx context.Context) error {
@@ -35,26 +51,135 @@ func Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	defer store.CloseLogStore()
+
+	ensureAggrChannels(ctx)
+
 	loggerMap := store.NewSyncLoggerMap()
 
-	eg, cctx := errgroup.WithContext(ctx)
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	eg, cctx := errgroup.WithContext(sigCtx)
+
+	if *metricsListen != "" {
+		eg.Go(func() error {
+			if err := serveMetrics(cctx, *metricsListen); err != nil {
+				logrus.Warnf("failed to serve metrics: %+v", err)
+			}
+			return nil
+		})
+	}
+
+	eg.Go(func() error {
+		store.RunLogSweeper(cctx)
+		return nil
+	})
+	if listen := config.GetConfig().Events.Listen; listen != "" {
+		eg.Go(func() error {
+			if err := ingest.DefaultEventsServer.ListenAndServe(cctx, listen); err != nil {
+				return fmt.Errorf("failed to serve events api: %w", err)
+			}
+			return nil
+		})
+	}
 
+	toChanged := make(chan struct{}, 1)
 	eg.Go(func() error {
-		if err := reply.HandleReplyMessage(cctx, loggerMap); err != nil {
+		if err := handleReplyMessage(cctx, loggerMap, toChanged); err != nil {
 			return fmt.Errorf("failed to handle reply message: %w", err)
 		}
 		return nil
 	})
+	reloaded := make(chan *config.Diff)
+	eg.Go(func() error {
+		watchConfig(cctx, *configPath, *watchInterval, reloaded, toChanged)
+		return nil
+	})
 	eg.Go(func() error {
-		if err := aggregate.StartCatchMessage(cctx, loggerMap); err != nil {
+		if err := aggregate.StartCatchMessage(cctx, loggerMap, reloaded); err != nil {
 			return fmt.Errorf("failed to catch message: %w", err)
 		}
 		return nil
 	})
 
-	if err := eg.Wait(); err != nil {
-		return fmt.Errorf("failed to wait errgroup: %w", err)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- eg.Wait()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("failed to wait errgroup: %w", err)
+		}
+		return nil
+	case <-sigCtx.Done():
+	}
+
+	// received signal, drain in-flight messages
+	logrus.Infof("shutting down, wait in-flight messages (timeout: %s)", *drainTimeout)
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("failed to wait errgroup: %w", err)
+		}
+	case <-time.After(*drainTimeout):
+		return fmt.Errorf("timeout of waiting in-flight messages")
 	}
 
 	return nil
 }
+
+// handleReplyMessage run listener of reply, and restart it when [to] is changed
+func handleReplyMessage(ctx context.Context, loggerMap *store.SyncLoggerMap, toChanged <-chan struct{}) error {
+	for {
+		rctx, cancel := context.WithCancel(ctx)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- reply.HandleReplyMessage(rctx, loggerMap)
+		}()
+
+		select {
+		case err := <-errCh:
+			cancel()
+			return err
+		case <-toChanged:
+			cancel()
+			if err := <-errCh; err != nil {
+				return err
+			}
+			logrus.Infof("restarted listener of reply because [to] is changed")
+		}
+	}
+}
+
+// serveMetrics serve metrics (/debug/vars) on addr until ctx is done
+func serveMetrics(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: http.DefaultServeMux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(sctx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ensureAggrChannels create missing aggregated channels and report it
+func ensureAggrChannels(ctx context.Context) {
+	created, err := utils.EnsureAggrChannels(ctx, store.GetConfigToAPI())
+	for _, name := range created {
+		logrus.Infof("created aggregated channel: #%s", name)
+	}
+	if err != nil {
+		logrus.Warnf("failed to create aggregated channels: %+v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
+)
+
+// watchConfig reload config when receive SIGHUP or content of config is changed,
+// and send difference to reloaded and notify toChanged if [to] is changed. if interval is 0, content is not watched.
+func watchConfig(ctx context.Context, configPath string, interval time.Duration, reloaded chan<- *config.Diff, toChanged chan<- struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	last, err := config.Fetch(configPath)
+	if err != nil {
+		logrus.Warnf("failed to fetch config: %+v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logrus.Infof("received SIGHUP, reload config")
+			// keep content, so that same content is not reloaded again by watching
+			if b, err := config.Fetch(configPath); err == nil {
+				last = b
+			}
+		case <-tick:
+			b, err := config.Fetch(configPath)
+			if err != nil {
+				logrus.Warnf("failed to fetch config: %+v", err)
+				continue
+			}
+			if bytes.Equal(last, b) {
+				continue
+			}
+			last = b
+			logrus.Infof("detected change of config, reload config")
+		}
+
+		diff, err := config.ReloadConfig(configPath)
+		if err != nil {
+			logrus.Warnf("failed to reload config: %+v", err)
+			continue
+		}
+		// release cache of api that is replaced or removed
+		utils.PruneDirectories()
+		ensureAggrChannels(ctx)
+		if err := utils.RefreshAggrChannels(ctx, store.GetConfigToAPI()); err != nil {
+			logrus.Warnf("failed to refresh aggregated channels: %+v", err)
+		}
+		if diff.IsEmpty() {
+			continue
+		}
+		if diff.ToChanged {
+			select {
+			case toChanged <- struct{}{}:
+			default:
+				// restarting is already notified
+			}
+		}
+		if diff.StoreChanged {
+			logrus.Warnf("type or path of [store] is changed, it require restarting")
+		}
+
+		select {
+		case reloaded <- diff:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -2,6 +2,7 @@ package aggregate
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -9,41 +10,109 @@ import (
 	"github.com/slack-go/slack"
 	"github.com/spf13/cast"
 	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/ingest"
 	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
 	"github.com/whywaita/slackrus"
 )
 
-func handleCatchMessagePerWorkspace(ctx context.Context, workspaceName, token string, loggerMap *store.SyncLoggerMap) {
-	var lastTimestamp string
+var (
+	// ErrInvalidAuth is error message for token of source slack is invalid
+	ErrInvalidAuth = fmt.Errorf("token is invalid or account is inactive")
+	// ErrListenerTerminated is error message for connection of source slack is terminated
+	ErrListenerTerminated = fmt.Errorf("listener is terminated")
+)
 
+func newWorkspaceLogger(workspaceName string) *logrus.Logger {
 	logger := logrus.New()
-	logger.AddHook(&slackrus.SlackrusHook{
+	logger.ReplaceHooks(newWorkspaceHooks(workspaceName))
+	logger.SetLevel(logrus.DebugLevel)
+
+	return logger
+}
+
+// newWorkspaceHooks create hooks that report to aggregated channel of workspace by current [to]
+func newWorkspaceHooks(workspaceName string) logrus.LevelHooks {
+	hooks := logrus.LevelHooks{}
+	hooks.Add(&slackrus.SlackrusHook{
 		LegacyToken:    store.GetConfigToAPIToken(),
 		AcceptedLevels: slackrus.LevelThreshold(logrus.WarnLevel),
 		IconEmoji:      ":ghost:",
 		Username:       "aguri",
 		Channel:        config.GetToChannelName(workspaceName),
 	})
-	logger.SetLevel(logrus.DebugLevel)
-	loggerMap.Store(workspaceName, logger)
 
-	fromAPI := slack.New(token)
-	rtm := fromAPI.NewRTM(slack.RTMOptionUseStart(false))
-	go rtm.ManageConnection()
-	for msg := range rtm.IncomingEvents {
+	return hooks
+}
+
+func isAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "invalid_auth") || strings.Contains(msg, "account_inactive") || strings.Contains(msg, "token_revoked")
+}
+
+// handleCatchMessagePerWorkspace handle events until ctx is done or listener is terminated
+func handleCatchMessagePerWorkspace(ctx context.Context, workspaceName string, logger *logrus.Logger, onConnected func()) error {
+	var lastTimestamp string
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// share api instance with reply, so that cache of directory is shared
+	fromAPI := store.GetSlackAPIInstance(workspaceName)
+	events, err := ingest.Stream(ctx, fromAPI, config.GetFrom(workspaceName).IngestOptions(workspaceName))
+	if err != nil {
+		return fmt.Errorf("failed to start receiving events: %w", err)
+	}
+	go replayPeriodically(ctx, fromAPI, workspaceName, logger)
+
+	for msg := range events {
+		if ctx.Err() != nil {
+			// shutting down, not handle buffered events
+			break
+		}
+
+		if utils.HandleDirectoryEvent(fromAPI, msg) {
+			continue
+		}
+
 		switch ev := msg.Data.(type) {
 		case *slack.ConnectedEvent:
-			// info = ev.Info
+			onConnected()
+			go func() {
+				if err := utils.GetDirectory(fromAPI).Warm(ctx); err != nil {
+					logger.Infof("failed to warm up directory: %+v", err)
+				}
+			}()
+			// post messages that are missed while disconnected, live events of backfilling channels are kept
+			Backfill(ctx, fromAPI, workspaceName, logger)
+			// deliver events that are lost while aggregated slack or this process is down
+			go func() {
+				if err := ReplayOutbox(ctx, fromAPI, workspaceName, logger); err != nil {
+					logger.Warn(err)
+				}
+			}()
+		case *slack.InvalidAuthEvent:
+			return ErrInvalidAuth
 		case *slack.MessageEvent:
-			lastTimestamp = HandleMessageEvent(ctx, ev, fromAPI, workspaceName, lastTimestamp, logger)
+			// complete posting even if shutting down
+			lastTimestamp = HandleMessageEvent(utils.WithoutCancel(ctx), ev, fromAPI, workspaceName, lastTimestamp, logger)
+		case *slack.ReactionAddedEvent, *slack.ReactionRemovedEvent:
+			if r, ok := utils.ParseReactionEvent(ev); ok {
+				if err := HandleReactionEvent(utils.WithoutCancel(ctx), fromAPI, workspaceName, r); err != nil {
+					logger.Warn(err)
+				}
+			}
+		case *slack.FileDeletedEvent:
+			if err := utils.DeleteMirroredFile(utils.WithoutCancel(ctx), store.GetConfigToAPI(), workspaceName, ev.FileID); err != nil {
+				logger.Warn(err)
+			}
 		case *slack.RTMError:
 			logger.Infof("RTM Error: %s\n", ev.Error())
-		case *slack.FilePublicEvent,
-			*slack.ReactionAddedEvent,
-			*slack.ReactionRemovedEvent,
-			*slack.MemberJoinedChannelEvent,
-			*slack.MemberLeftChannelEvent,
-			*slack.TeamJoinEvent:
+		case *slack.MemberJoinedChannelEvent,
+			*slack.MemberLeftChannelEvent:
 			// not implement events
 			logger.Debugf("Not Implement Event Type: %v, Data: %+v\n", msg.Type, msg.Data)
 		case *slack.HelloEvent,
@@ -55,16 +124,23 @@ func handleCatchMessagePerWorkspace(ctx context.Context, workspaceName, token st
 			*slack.GroupMarkedEvent,
 			*slack.IncomingEventError,
 			*slack.DisconnectedEvent,
-			*slack.UserChangeEvent,
 			*slack.DNDUpdatedEvent,
 			*slack.PrefChangeEvent,
 			*slack.ChannelJoinedEvent,
 			*slack.ChannelLeftEvent,
-			*slack.AccountsChangedEvent:
-			// ignore events
+			*slack.AccountsChangedEvent,
+			*slack.FilePublicEvent,
+			*slack.FileSharedEvent,
+			*slack.FileCreatedEvent,
+			*slack.FileChangeEvent:
+			// ignore events, shared files are mirrored by message event
 		case *slack.ConnectionErrorEvent:
+			if isAuthError(ev.ErrorObj) {
+				return fmt.Errorf("%w: %s", ErrInvalidAuth, ev.Error())
+			}
 			if strings.Contains(cast.ToString(msg.Data), "slack rate limit exceeded") {
-				// ignore rate limit event
+				// rate limited in connecting, connection is retried by slack-go
+				logger.Infof("rate limited in connecting: %s", ev.Error())
 				break
 			}
 			logger.Warnf("Unexpected Event Type: %v, Data: %+v\n", msg.Type, msg.Data)
@@ -72,24 +148,79 @@ func handleCatchMessagePerWorkspace(ctx context.Context, workspaceName, token st
 			logger.Warnf("Unexpected Event Type: %v, Data: %+v\n", msg.Type, msg.Data)
 		}
 	}
+	if ctx.Err() != nil {
+		logger.Debugf("stop receiving events (workspace: %s)", workspaceName)
+		return nil
+	}
+
+	return ErrListenerTerminated
+}
+
+type worker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
-// StartCatchMessage start handler of message
-func StartCatchMessage(ctx context.Context, loggerMap *store.SyncLoggerMap) error {
+// StartCatchMessage start handler of message.
+// When config is reloaded, listeners of added workspaces are started and listeners of removed workspaces are stopped.
+func StartCatchMessage(ctx context.Context, loggerMap *store.SyncLoggerMap, reloaded <-chan *config.Diff) error {
 	var wg sync.WaitGroup
+	workers := map[string]*worker{}
+
+	start := func(team string) {
+		wctx, cancel := context.WithCancel(ctx)
+		w := &worker{cancel: cancel, done: make(chan struct{})}
+		workers[team] = w
 
-	froms := store.GetConfigFromAPITokens()
-	for team, token := range froms {
 		wg.Add(1)
-		// pass goroutine miss ref: http://qiita.com/sudix/items/67d4cad08fe88dcb9a6d
-		fromToken := token
-		fromTeam := team
 		go func() {
-			handleCatchMessagePerWorkspace(ctx, fromTeam, fromToken, loggerMap)
-			wg.Done()
+			defer wg.Done()
+			defer close(w.done)
+			superviseWorkspace(wctx, team, loggerMap)
 		}()
 	}
-	wg.Wait()
+	stop := func(team string) {
+		w, ok := workers[team]
+		if !ok {
+			return
+		}
+		w.cancel()
+		<-w.done // wait in-flight message
+		delete(workers, team)
+	}
+
+	for team := range store.GetConfigFromAPITokens() {
+		start(team)
+	}
 
-	return nil
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case diff := <-reloaded:
+			for _, team := range diff.Removed {
+				stop(team)
+				loggerMap.Delete(team)
+				logrus.Infof("stopped listener of removed workspace: %s", team)
+			}
+			for _, team := range diff.Changed {
+				stop(team)
+				start(team)
+				logrus.Infof("restarted listener of changed workspace: %s", team)
+			}
+			for _, team := range diff.Added {
+				start(team)
+				logrus.Infof("started listener of added workspace: %s", team)
+			}
+			if diff.ToChanged {
+				// rotate token and channel of hooks, restarted listeners already have new one
+				for team := range workers {
+					if logger, err := loggerMap.Load(team); err == nil {
+						logger.ReplaceHooks(newWorkspaceHooks(team))
+					}
+				}
+			}
+		}
+	}
 }
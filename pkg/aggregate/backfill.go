@@ -0,0 +1,93 @@
+package aggregate
+
+import (
+	"context"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
+)
+
+const (
+	defaultBackfillMaxMessages = 500
+)
+
+// Backfill post messages that are posted while disconnected in background, in order of timestamp.
+// only channels that have processed messages before are backfilled.
+// live events of backfilling channels are kept in outbox, and delivered after backfilled messages.
+func Backfill(ctx context.Context, fromAPI *slack.Client, workspace string, logger *logrus.Logger) {
+	c := config.GetConfig().Backfill
+	if c.Disable {
+		return
+	}
+	maxMessages := c.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = defaultBackfillMaxMessages
+	}
+
+	cursors, err := store.GetCursors(workspace)
+	if err != nil {
+		logger.Warn(err)
+		return
+	}
+	var channels []string
+	for channelID := range cursors {
+		channels = append(channels, channelID)
+	}
+	sort.Strings(channels)
+
+	// mark before returning, so that events received after this are kept
+	d := getDelivery(workspace)
+	d.startBackfilling(channels)
+
+	go func() {
+		d.backfillMu.Lock()
+		defer d.backfillMu.Unlock()
+
+		for _, channelID := range channels {
+			if ctx.Err() == nil {
+				backfillChannel(ctx, fromAPI, workspace, channelID, cursors[channelID], maxMessages, logger)
+			}
+			if err := d.finishBackfilling(workspace, channelID); err != nil {
+				logger.Warn(err)
+			}
+			if ctx.Err() != nil {
+				// kept events are replayed in next start
+				continue
+			}
+			if err := ReplayOutbox(ctx, fromAPI, workspace, logger); err != nil {
+				logger.Infof("%+v", err)
+			}
+		}
+	}()
+}
+
+// backfillChannel post messages of channel that posted after cursor
+func backfillChannel(ctx context.Context, fromAPI *slack.Client, workspace, channelID, cursor string, maxMessages int, logger *logrus.Logger) {
+	messages, hasMore, err := utils.GetMessagesAfter(ctx, fromAPI, channelID, cursor, maxMessages)
+	if err != nil {
+		// e.g. left or archived channel
+		logger.Infof("failed to backfill: %+v", err)
+		return
+	}
+	if hasMore {
+		logger.Warnf("too many missed messages in %s, backfill only oldest %d messages", channelID, maxMessages)
+	}
+	if len(messages) > 0 {
+		logger.Infof("backfill %d messages in %s (workspace: %s)", len(messages), channelID, workspace)
+	}
+
+	for _, m := range messages {
+		if ctx.Err() != nil {
+			return
+		}
+		ev := slack.MessageEvent(m)
+		ev.Type = "message"
+		ev.Channel = channelID
+		// already processed messages are skipped by outbox
+		HandleMessageEvent(utils.WithBackfill(utils.WithoutCancel(ctx)), &ev, fromAPI, workspace, "", logger)
+	}
+}
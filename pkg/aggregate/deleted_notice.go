@@ -0,0 +1,60 @@
+package aggregate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// bulkDeleteWindow is duration that following deletions are appended to notice of deleted messages
+	bulkDeleteWindow = 10 * time.Second
+	// maxBulkDeleteMessages is max number of deleted messages in a notice
+	maxBulkDeleteMessages = 20
+)
+
+// deletedNotice is aggregated message that notice deleted messages in repost mode
+type deletedNotice struct {
+	channelID string
+	timestamp string
+	bodies    []string
+	updatedAt time.Time
+}
+
+var (
+	deletedNotices   = map[string]*deletedNotice{} // key: workspace and source channel id
+	deletedNoticesMu sync.Mutex
+)
+
+func (n *deletedNotice) text() string {
+	return fmt.Sprintf("Original Text of %d deleted messages:\n%s", len(n.bodies), strings.Join(n.bodies, "\n\n"))
+}
+
+// recentDeletedNotice get notice of deleted messages in source channel that can be appended
+func recentDeletedNotice(workspace, channelID string) (*deletedNotice, bool) {
+	deletedNoticesMu.Lock()
+	defer deletedNoticesMu.Unlock()
+
+	key := workspace + "," + channelID
+	n, ok := deletedNotices[key]
+	if !ok || time.Since(n.updatedAt) > bulkDeleteWindow || len(n.bodies) >= maxBulkDeleteMessages {
+		delete(deletedNotices, key)
+		return nil, false
+	}
+	n.updatedAt = time.Now()
+	return n, true
+}
+
+// setDeletedNotice register posted notice of deleted message
+func setDeletedNotice(workspace, channelID, aggrChannelID, timestamp, body string) {
+	deletedNoticesMu.Lock()
+	defer deletedNoticesMu.Unlock()
+
+	deletedNotices[workspace+","+channelID] = &deletedNotice{
+		channelID: aggrChannelID,
+		timestamp: timestamp,
+		bodies:    []string{body},
+		updatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,132 @@
+package aggregate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
+)
+
+const (
+	// replayInterval is interval of replaying outbox while listener is running
+	replayInterval = 1 * time.Minute
+)
+
+// delivery serialize delivering events of workspace between listener, replaying outbox and backfilling.
+// events of channel that has undelivered events or is in backfilling are kept in outbox,
+// so that order in channel is preserved.
+type delivery struct {
+	mu          sync.Mutex      // held while delivering an event
+	blocked     map[string]bool // key: id of source channel that has undelivered events
+	backfilling map[string]int  // key: id of source channel, value: number of running backfills
+
+	replayMu   sync.Mutex // held while replaying outbox
+	backfillMu sync.Mutex // held while backfilling
+}
+
+var (
+	deliveries   = map[string]*delivery{}
+	deliveriesMu sync.Mutex
+)
+
+// getDelivery get delivery of workspace, channels that have undelivered events in previous run are blocked
+func getDelivery(workspace string) *delivery {
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
+
+	d, ok := deliveries[workspace]
+	if !ok {
+		d = &delivery{blocked: map[string]bool{}, backfilling: map[string]int{}}
+		if err := d.refresh(workspace); err != nil {
+			logrus.Warnf("failed to get undelivered events (workspace: %s): %+v", workspace, err)
+		}
+		deliveries[workspace] = d
+	}
+	return d
+}
+
+// refresh block channels that have undelivered events, d.mu must be held
+func (d *delivery) refresh(workspace string) error {
+	items, err := store.PendingOutbox(workspace)
+	if err != nil {
+		return err
+	}
+
+	d.blocked = map[string]bool{}
+	for _, item := range items {
+		d.blocked[item.Channel] = true
+	}
+	return nil
+}
+
+// isQueued check event must be kept in outbox, d.mu must be held.
+// messages of backfilling are delivered in backfilling channel, but live events are not.
+func (d *delivery) isQueued(ctx context.Context, channelID string) bool {
+	return d.blocked[channelID] || (d.backfilling[channelID] > 0 && !utils.IsBackfill(ctx))
+}
+
+// deliverable filter events that can be replayed now, d.mu must be held
+func (d *delivery) deliverable(items []store.OutboxItem) []store.OutboxItem {
+	var filtered []store.OutboxItem
+	for _, item := range items {
+		if d.backfilling[item.Channel] == 0 {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// startBackfilling mark channels as backfilling, so that live events of them are kept in outbox
+func (d *delivery) startBackfilling(channelIDs []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ch := range channelIDs {
+		d.backfilling[ch]++
+	}
+}
+
+// finishBackfilling unmark channel, kept events of it are blocked until replayed
+func (d *delivery) finishBackfilling(workspace, channelID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.backfilling[channelID]--
+	if d.backfilling[channelID] <= 0 {
+		delete(d.backfilling, channelID)
+	}
+	return d.refresh(workspace)
+}
+
+// hasBlocked check some channels have undelivered events
+func (d *delivery) hasBlocked() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.blocked) != 0
+}
+
+// replayPeriodically replay outbox until ctx is done,
+// so that events are delivered after aggregated slack is recovered even if source slack keep connected
+func replayPeriodically(ctx context.Context, fromAPI *slack.Client, workspace string, logger *logrus.Logger) {
+	ticker := time.NewTicker(replayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !getDelivery(workspace).hasBlocked() {
+			continue
+		}
+		if err := ReplayOutbox(ctx, fromAPI, workspace, logger); err != nil {
+			// aggregated slack is still down, reporting to it is useless
+			logger.Infof("%+v", err)
+		}
+	}
+}
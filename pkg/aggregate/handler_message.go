@@ -2,10 +2,12 @@ package aggregate
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/sirupsen/logrus"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackutilsx"
 	"github.com/whywaita/aguri/pkg/config"
 	"github.com/whywaita/aguri/pkg/store"
 	"github.com/whywaita/aguri/pkg/utils"
@@ -16,87 +18,256 @@ var (
 	ErrAttachmentNotFound = fmt.Errorf("Detect Link Expand, but Attachment is not found")
 )
 
-// HandleMessageEvent handle message event
+// HandleMessageEvent handle message event.
+// event is recorded to outbox before delivering, and it is replayed by ReplayOutbox if aggregated slack is down.
 func HandleMessageEvent(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client, workspace, lastTimestamp string, logger *logrus.Logger) string {
-	var err error
-
-	if lastTimestamp != ev.Timestamp {
+	if lastTimestamp == ev.Timestamp {
 		// if lastTimestamp == ev.Timestamp, that message is same.
-		toChannelName := config.GetToChannelName(workspace)
-
-		switch ev.SubType {
-		case "message_changed":
-			switch {
-			case len(ev.SubMessage.Attachments) == 0:
-				err = handleMessageEdited(ctx, ev, fromAPI, workspace, toChannelName)
-				if err != nil {
-					logger.Warn(err)
-					break
-				}
-
-			case len(ev.SubMessage.Attachments) >= 1:
-				// message_changed and Text is null = URL link expand
-				if err = handleMessageLinkExpand(ctx, ev, fromAPI, workspace, logger); err != nil && err != ErrAttachmentNotFound {
-					logger.Warn(err)
-					break
-				}
-			}
+		return lastTimestamp
+	}
 
-		case "message_deleted":
-			err = handleMessageDeleted(ctx, ev, fromAPI, workspace, toChannelName)
-			if err != nil {
-				logger.Warn(err)
-			}
-		default:
-			err = utils.PostMessageToChannel(ctx, store.GetConfigToAPI(), fromAPI, ev, ev.Text, toChannelName)
-			if err != nil {
-				logger.Warn(err)
-			}
+	defer func() {
+		// messages before this are processed, or recorded to outbox
+		if err := store.AdvanceCursor(workspace, ev.Channel, ev.Timestamp); err != nil {
+			logger.Warn(err)
 		}
+	}()
 
+	d := getDelivery(workspace)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	added, err := store.AddOutbox(workspace, ev, utils.IsBackfill(ctx))
+	switch {
+	case err != nil:
+		// deliver even if outbox is unavailable
+		logger.Warn(err)
+	case !added:
+		logger.Debugf("skip duplicated event (workspace: %s, timestamp: %s)", workspace, ev.Timestamp)
 		return ev.Timestamp
+	case d.isQueued(ctx, ev.Channel):
+		// older events in channel are not delivered yet, this is delivered after them by ReplayOutbox
+		return ev.Timestamp
+	}
+
+	err = deliverMessageEvent(ctx, ev, fromAPI, workspace, logger)
+	if utils.IsDeliveryError(err) {
+		d.blocked[ev.Channel] = true
+		logger.Warnf("%v (it will be delivered again later)", err)
+		return ev.Timestamp
+	}
+	if err != nil {
+		// not caused by aggregated slack, so retrying is useless
+		logger.Warn(err)
+	}
+	if err := store.DoneOutbox(workspace, ev.Timestamp); err != nil {
+		logger.Warn(err)
+	}
+
+	return ev.Timestamp
+}
+
+// deliverMessageEvent post message event to aggregated slack
+func deliverMessageEvent(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client, workspace string, logger *logrus.Logger) error {
+	kind, channelName := resolveSourceChannel(ctx, ev, fromAPI)
+	if !config.GetFilter(workspace).Allow(kind, channelName) {
+		return nil
 	}
+	toChannelName := config.ResolveDestination(workspace, kind, channelName)
 
-	return lastTimestamp
+	switch ev.SubType {
+	case "message_changed":
+		if ev.SubMessage.SubType == "tombstone" {
+			// parent of thread is deleted, but replies are kept
+			return handleMessageDeleted(ctx, ev, fromAPI, workspace, toChannelName, ev.SubMessage.Timestamp, logger)
+		}
+		if len(ev.SubMessage.Attachments) == 0 {
+			return handleMessageEdited(ctx, ev, fromAPI, workspace, toChannelName)
+		}
+		// message_changed and Text is null = URL link expand
+		if err := handleMessageLinkExpand(ctx, ev, fromAPI, workspace, logger); err != nil && err != ErrAttachmentNotFound {
+			return err
+		}
+		return nil
+	case "message_deleted":
+		return handleMessageDeleted(ctx, ev, fromAPI, workspace, toChannelName, ev.DeletedTimestamp, logger)
+	default:
+		return utils.PostMessageToChannel(ctx, store.GetConfigToAPI(), fromAPI, ev, ev.Text, workspace, toChannelName)
+	}
 }
 
-func handleMessageDeleted(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client, workspace, toChannelName string) error {
-	d, err := store.GetSlackLog(workspace, ev.DeletedTimestamp)
+// resolveSourceChannel get kind and name of source channel for filter and routing
+func resolveSourceChannel(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client) (kind, channelName string) {
+	fromType, name, err := utils.ConvertDisplayChannelName(ctx, fromAPI, ev)
 	if err != nil {
+		// can't get channel name (e.g. DM of bot), so use channel id
+		fromType = slackutilsx.DetectChannelType(ev.Channel).String()
+		name = ev.Channel
+	}
+
+	return utils.ConvertChannelKind(fromType, name), name
+}
+
+func handleMessageDeleted(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client, workspace, toChannelName, deletedTimestamp string, logger *logrus.Logger) error {
+	d, err := utils.LookupSourceSlackLog(ctx, store.GetConfigToAPI(), fromAPI, workspace, ev.Channel, deletedTimestamp, ev.PreviousMessage)
+
+	mode := config.GetDeleteMode(workspace)
+	if mode != config.DeleteModeRepost {
+		if errors.Is(err, store.ErrSlackLogEvicted) {
+			// aggregated message is too old, keep it
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get slack log from memory: %w", err)
+		}
+		return mirrorMessageDeleted(ctx, d, mode, logger)
+	}
+
+	var body string
+	switch {
+	case errors.Is(err, store.ErrSlackLogEvicted):
+		body = "(already expired)"
+	case err != nil:
 		return fmt.Errorf("failed to get slack log from memory: %w", err)
+	default:
+		body = d.Body
 	}
 
-	msg := fmt.Sprintf("Original Text:\n%v", d.Body)
+	// bulk deletion is appended to recent notice, so that channel is not flooded
+	if n, ok := recentDeletedNotice(workspace, ev.Channel); ok {
+		n.bodies = append(n.bodies, utils.RewriteMrkdwn(ctx, fromAPI, workspace, body))
+		if err := utils.UpdateMessageQueued(ctx, store.GetConfigToAPI(), n.channelID, n.timestamp, slack.MsgOptionText(n.text(), false)); err != nil {
+			return fmt.Errorf("failed to update notice of deleted messages: %w", err)
+		}
+		return nil
+	}
 
-	err = utils.PostMessageToChannel(ctx, store.GetConfigToAPI(), fromAPI, ev, msg, toChannelName)
+	msg := fmt.Sprintf("Original Text:\n%v", body)
+	if errors.Is(err, store.ErrSlackLogEvicted) {
+		msg = "Message is deleted, but original text is already expired"
+	}
+	err = utils.PostMessageToChannel(ctx, store.GetConfigToAPI(), fromAPI, ev, msg, workspace, toChannelName)
 	if err != nil {
 		return fmt.Errorf("failed to post message: %w", err)
 	}
+	if posted, err := store.GetSlackLog(workspace, ev.Timestamp); err == nil {
+		setDeletedNotice(workspace, ev.Channel, posted.ToAPIChannelID, posted.ToAPITimestamp, utils.RewriteMrkdwn(ctx, fromAPI, workspace, body))
+	}
 
 	return nil
 }
 
+// mirrorMessageDeleted delete or update aggregated messages of deleted source message
+func mirrorMessageDeleted(ctx context.Context, d *store.LogData, mode string, logger *logrus.Logger) error {
+	toAPI := store.GetConfigToAPI()
+
+	if mode == config.DeleteModeDelete && d.SourceThreadTimestamp == "" {
+		// aggregated message may be parent of thread, deleting it makes replies hard to read
+		hasReplies, err := utils.HasReplies(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp)
+		if err != nil {
+			logger.Infof("failed to check replies of aggregated message, replace it instead of deleting: %+v", err)
+		}
+		if err != nil || hasReplies {
+			mode = config.DeleteModeReplace
+		}
+	}
+
+	switch mode {
+	case config.DeleteModeDelete:
+		for _, ts := range append([]string{d.ToAPITimestamp}, d.ToAPIExtraTimestamps...) {
+			if err := utils.DeleteMessageQueued(ctx, toAPI, d.ToAPIChannelID, ts); err != nil {
+				return fmt.Errorf("failed to delete message: %w", err)
+			}
+		}
+		return nil
+	case config.DeleteModeStrike:
+		if err := utils.UpdateMessageQueued(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp, slack.MsgOptionText(utils.StrikeThrough(d.Body), false)); err != nil {
+			return fmt.Errorf("failed to update message: %w", err)
+		}
+		return nil
+	default:
+		if err := utils.UpdateMessageQueued(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp, slack.MsgOptionText("[deleted]", false)); err != nil {
+			return fmt.Errorf("failed to update message: %w", err)
+		}
+		return nil
+	}
+}
+
 func handleMessageEdited(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client, workspace, toChannelName string) error {
-	d, err := store.GetSlackLog(workspace, ev.SubMessage.Timestamp)
+	d, err := utils.LookupSourceSlackLog(ctx, store.GetConfigToAPI(), fromAPI, workspace, ev.Channel, ev.SubMessage.Timestamp, ev.PreviousMessage)
+	if errors.Is(err, store.ErrSlackLogEvicted) {
+		// original text is expired, so post only edited text
+		msg := "Edited From:\n(already expired)\n\nEdited To:\n" + ev.SubMessage.Text
+		if err := utils.PostMessageToChannel(ctx, store.GetConfigToAPI(), fromAPI, ev, msg, workspace, toChannelName); err != nil {
+			return fmt.Errorf("failed to post message: %w", err)
+		}
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get slack log from memory: %w", err)
 	}
 
-	msg := fmt.Sprintf("Edited From:\n%v", d.Body)
-	msg += "\n\nEdited To:\n" + ev.SubMessage.Text
+	switch mode := config.GetEditMode(workspace); mode {
+	case config.EditModeUpdate, config.EditModeDiff, config.EditModeBoth:
+		body, err := mirrorMessageEdited(ctx, ev, fromAPI, workspace, d, mode)
+		if err != nil {
+			return err
+		}
+		d.Body = body
+	default:
+		msg := fmt.Sprintf("Edited From:\n%v", d.Body)
+		msg += "\n\nEdited To:\n" + ev.SubMessage.Text
 
-	err = utils.PostMessageToChannel(ctx, store.GetConfigToAPI(), fromAPI, ev, msg, toChannelName)
-	if err != nil {
-		return fmt.Errorf("failed to post message: %w", err)
+		err = utils.PostMessageToChannel(ctx, store.GetConfigToAPI(), fromAPI, ev, msg, workspace, toChannelName)
+		if err != nil {
+			return fmt.Errorf("failed to post message: %w", err)
+		}
+		// keep converted text like update and diff mode, it is compared with next edit
+		d.Body = utils.RewriteMrkdwn(ctx, fromAPI, workspace, ev.SubMessage.Text)
 	}
 
-	store.SetSlackLog(workspace, ev.SubMessage.Timestamp, d.Channel, ev.SubMessage.Text, "", "")
+	if err := store.PutSlackLog(workspace, ev.SubMessage.Timestamp, *d); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// mirrorMessageEdited update aggregated message and/or post diff to thread of it, and return edited text
+func mirrorMessageEdited(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client, workspace string, d *store.LogData, mode string) (string, error) {
+	toAPI := store.GetConfigToAPI()
+
+	text := utils.RewriteMrkdwn(ctx, fromAPI, workspace, ev.SubMessage.Text)
+
+	if mode == config.EditModeUpdate || mode == config.EditModeBoth {
+		blocks, err := utils.ConvertBlocks(ctx, fromAPI, workspace, ev.SubMessage.Blocks)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert blocks: %w", err)
+		}
+		opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+		if len(blocks) != 0 {
+			opts = append(opts, slack.MsgOptionBlocks(blocks...))
+		}
+		if err := utils.UpdateMessageQueued(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp, opts...); err != nil {
+			return "", fmt.Errorf("failed to update message: %w", err)
+		}
+	}
+	if mode == config.EditModeDiff || mode == config.EditModeBoth {
+		msg := "Edited:\n```\n" + utils.LineDiff(d.Body, text) + "\n```"
+		if _, err := utils.PostSystemMessageInThread(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp, msg); err != nil {
+			return "", fmt.Errorf("failed to post diff: %w", err)
+		}
+	}
+
+	return text, nil
+}
+
 func handleMessageLinkExpand(ctx context.Context, ev *slack.MessageEvent, fromAPI *slack.Client, workspace string, logger *logrus.Logger) error {
-	d, err := store.GetSlackLog(workspace, ev.SubMessage.Timestamp)
+	d, err := utils.LookupSourceSlackLog(ctx, store.GetConfigToAPI(), fromAPI, workspace, ev.Channel, ev.SubMessage.Timestamp, ev.SubMessage)
+	if errors.Is(err, store.ErrSlackLogEvicted) {
+		// too old message, not need to expand
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get slack log from memory: %w", err)
 	}
@@ -105,7 +276,7 @@ func handleMessageLinkExpand(ctx context.Context, ev *slack.MessageEvent, fromAP
 	case len(ev.SubMessage.Attachments) == 0:
 		return ErrAttachmentNotFound
 	}
-	if _, _, _, err = store.GetConfigToAPI().UpdateMessageContext(ctx,
+	if err = utils.UpdateMessageQueued(ctx, store.GetConfigToAPI(),
 		d.ToAPIChannelID, d.ToAPITimestamp,
 		slack.MsgOptionText(d.Body, false),
 		slack.MsgOptionUpdate(d.ToAPITimestamp),
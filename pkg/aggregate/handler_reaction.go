@@ -0,0 +1,80 @@
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
+)
+
+// HandleReactionEvent mirror reaction of source message to aggregated message
+func HandleReactionEvent(ctx context.Context, fromAPI *slack.Client, workspace string, r utils.Reaction) error {
+	if utils.IsMirroredReaction(workspace, r.Channel, r.Timestamp, r.Name, r.Added) {
+		// mirrored from aggregated slack by aguri
+		return nil
+	}
+
+	d, err := store.GetSlackLog(workspace, r.Timestamp)
+	if errors.Is(err, store.ErrSourceChannelNotFound) || errors.Is(err, store.ErrSlackLogEvicted) {
+		// not aggregated message
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get slack log: %w", err)
+	}
+	toAPI := store.GetConfigToAPI()
+
+	if r.Added {
+		err := utils.AddReactionQueued(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp, r.Name)
+		if errors.Is(err, utils.ErrEmojiNotFound) {
+			// custom emoji is not exist in aggregated slack, so notice by text
+			name := r.User
+			if u, err := utils.GetDirectory(fromAPI).GetUserInfo(ctx, r.User); err == nil {
+				name = u.Name
+			}
+			msg := fmt.Sprintf("%s reacted with `:%s:`", name, r.Name)
+			ts, err := utils.PostSystemMessageInThread(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp, msg)
+			if err != nil {
+				return err
+			}
+
+			// remember note to delete it when reaction is removed
+			if d.ReactionNotes == nil {
+				d.ReactionNotes = map[string][]string{}
+			}
+			d.ReactionNotes[r.Name] = append(d.ReactionNotes[r.Name], ts)
+			return store.PutSlackLog(workspace, r.Timestamp, *d)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add reaction: %w", err)
+		}
+		return nil
+	}
+
+	// aggregated reaction is shared by all users of source slack
+	still, err := utils.HasReactionByOthers(ctx, fromAPI, r.Channel, r.Timestamp, r.Name, "")
+	if err != nil {
+		return err
+	}
+	if still {
+		return nil
+	}
+	if err := utils.RemoveReactionQueued(ctx, toAPI, d.ToAPIChannelID, d.ToAPITimestamp, r.Name); err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	notes, ok := d.ReactionNotes[r.Name]
+	if !ok {
+		return nil
+	}
+	for _, ts := range notes {
+		if err := utils.DeleteMessageQueued(ctx, toAPI, d.ToAPIChannelID, ts); err != nil {
+			return fmt.Errorf("failed to delete note of reaction: %w", err)
+		}
+	}
+	delete(d.ReactionNotes, r.Name)
+	return store.PutSlackLog(workspace, r.Timestamp, *d)
+}
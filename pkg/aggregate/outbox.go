@@ -0,0 +1,76 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
+)
+
+// ReplayOutbox deliver events that are not delivered yet, in order of timestamp.
+// replaying is stopped if aggregated slack is still down.
+func ReplayOutbox(ctx context.Context, fromAPI *slack.Client, workspace string, logger *logrus.Logger) error {
+	d := getDelivery(workspace)
+	d.replayMu.Lock()
+	defer d.replayMu.Unlock()
+
+	for {
+		// get in lock, so that events in delivering by listener are not included
+		d.mu.Lock()
+		items, err := store.PendingOutbox(workspace)
+		if err == nil {
+			// events of backfilling channels are replayed after backfilling
+			items = d.deliverable(items)
+		}
+		if err == nil && len(items) == 0 {
+			err = d.refresh(workspace)
+		}
+		d.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		logger.Infof("replay %d undelivered events (workspace: %s)", len(items), workspace)
+
+		for _, item := range items {
+			if ctx.Err() != nil {
+				// shutting down, rest of events are replayed in next start
+				return nil
+			}
+			if err := replayOutboxItem(ctx, d, fromAPI, workspace, item, logger); err != nil {
+				return err
+			}
+		}
+		// events of blocked channels may be recorded while replaying
+	}
+}
+
+func replayOutboxItem(ctx context.Context, d *delivery, fromAPI *slack.Client, workspace string, item store.OutboxItem, logger *logrus.Logger) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ev, err := item.MessageEvent()
+	if err != nil {
+		logger.Warnf("drop broken event in outbox (timestamp: %s): %+v", item.Timestamp, err)
+	} else {
+		// complete posting even if shutting down
+		dctx := utils.WithoutCancel(ctx)
+		if item.Backfill {
+			dctx = utils.WithBackfill(dctx)
+		}
+		err = deliverMessageEvent(dctx, ev, fromAPI, workspace, logger)
+		if utils.IsDeliveryError(err) {
+			return fmt.Errorf("failed to replay outbox: %w", err)
+		}
+		if err != nil {
+			logger.Warn(err)
+		}
+	}
+
+	return store.DoneOutbox(workspace, item.Timestamp)
+}
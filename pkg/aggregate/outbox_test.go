@@ -0,0 +1,128 @@
+package aggregate
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/store"
+)
+
+const testConfig = `
+[to]
+token = "xoxp-to"
+post_interval = "1ms"
+max_retries = 1
+
+[from.team1]
+token = "xoxp-from"
+`
+
+// fakeSlack is fake of Slack API that used by source and aggregated slack
+type fakeSlack struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	posted []string // text of posted messages
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+
+	f := &fakeSlack{}
+	reply := func(w http.ResponseWriter, v map[string]interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conversations.info", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]interface{}{"ok": true, "channel": map[string]interface{}{"id": "C01", "name": "general", "is_channel": true}})
+	})
+	mux.HandleFunc("/conversations.list", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]interface{}{"ok": true, "channels": []map[string]interface{}{{"id": "CA1", "name": "aggr-team1", "is_channel": true}}})
+	})
+	mux.HandleFunc("/users.info", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]interface{}{"ok": true, "user": map[string]interface{}{"id": "U01", "name": "alice"}})
+	})
+	mux.HandleFunc("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		text := r.FormValue("text")
+		if strings.Contains(text, "too long") {
+			// permanent error of Slack API
+			reply(w, map[string]interface{}{"ok": false, "error": "msg_too_long"})
+			return
+		}
+		f.mu.Lock()
+		f.posted = append(f.posted, text)
+		f.mu.Unlock()
+		reply(w, map[string]interface{}{"ok": true, "channel": "CA1", "ts": "1700000000.000100"})
+	})
+	f.Server = httptest.NewServer(mux)
+
+	return f
+}
+
+func setupFakeSlack(t *testing.T) (*fakeSlack, *slack.Client) {
+	t.Helper()
+
+	f := newFakeSlack(t)
+	t.Cleanup(f.Close)
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := ioutil.WriteFile(path, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadConfig(path); err != nil {
+		t.Fatalf("failed to load config: %+v", err)
+	}
+
+	// replace api instances that created by config
+	fromAPI := slack.New("xoxp-from", slack.OptionAPIURL(f.URL+"/"))
+	store.SetFromApis(map[string]*slack.Client{"team1": fromAPI})
+	store.SetConfigToAPI(slack.New("xoxp-to", slack.OptionAPIURL(f.URL+"/")))
+
+	return f, fromAPI
+}
+
+func TestReplayOutboxPermanentError(t *testing.T) {
+	f, fromAPI := setupFakeSlack(t)
+
+	events := []*slack.MessageEvent{
+		{Msg: slack.Msg{Type: "message", Channel: "C01", User: "U01", Text: "message is too long", Timestamp: "1600000000.000100"}},
+		{Msg: slack.Msg{Type: "message", Channel: "C01", User: "U01", Text: "hello", Timestamp: "1600000000.000200"}},
+	}
+	for _, ev := range events {
+		if _, err := store.AddOutbox("team1", ev, false); err != nil {
+			t.Fatalf("failed to add outbox: %+v", err)
+		}
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	if err := ReplayOutbox(context.Background(), fromAPI, "team1", logger); err != nil {
+		t.Fatalf("replay must not be stopped by permanent error: %+v", err)
+	}
+
+	pending, err := store.PendingOutbox("team1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("events are left in outbox: %+v", pending)
+	}
+	if len(f.posted) != 1 || f.posted[0] != "hello" {
+		t.Errorf("following event is not delivered: %v", f.posted)
+	}
+	if getDelivery("team1").hasBlocked() {
+		t.Errorf("channel is blocked by permanent error")
+	}
+}
@@ -0,0 +1,62 @@
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whywaita/aguri/pkg/store"
+)
+
+const (
+	minRestartBackoff = 1 * time.Second
+	maxRestartBackoff = 5 * time.Minute
+	// authRestartBackoff is first backoff for auth error, token may be re-issued by operator
+	authRestartBackoff = 1 * time.Minute
+	// stableDuration is duration that listener is regarded as stable, backoff is reset after this
+	stableDuration = 10 * time.Minute
+)
+
+// superviseWorkspace run handleCatchMessagePerWorkspace, and restart it with exponential backoff when terminated.
+// State transitions are reported to aggregated channel via logger.
+func superviseWorkspace(ctx context.Context, workspaceName string, loggerMap *store.SyncLoggerMap) {
+	logger := newWorkspaceLogger(workspaceName)
+	loggerMap.Store(workspaceName, logger)
+
+	backoff := minRestartBackoff
+	restarted := false
+	for {
+		started := time.Now()
+		onConnected := func() {
+			if restarted {
+				logger.Warnf("listener is recovered (workspace: %s)", workspaceName)
+				restarted = false
+			}
+		}
+
+		err := handleCatchMessagePerWorkspace(ctx, workspaceName, logger, onConnected)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) > stableDuration {
+			backoff = minRestartBackoff
+		}
+		if errors.Is(err, ErrInvalidAuth) && backoff < authRestartBackoff {
+			backoff = authRestartBackoff
+		}
+		logger.Warnf("listener is stopped (workspace: %s): %+v, restart after %s", workspaceName, err, backoff)
+		restarted = true
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxRestartBackoff {
+			backoff = maxRestartBackoff
+		}
+	}
+}
@@ -5,62 +5,289 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/ingest"
+	"github.com/whywaita/aguri/pkg/outbound"
 	"github.com/whywaita/aguri/pkg/store"
 )
 
 const (
-	// PrefixSlackChannel is prefix of aggregated messages
+	// PrefixSlackChannel is default prefix of aggregated channel
 	PrefixSlackChannel = "aggr-"
 )
 
+var (
+	current   Config
+	currentMu sync.RWMutex
+)
+
 // Config is config of aguri
 type Config struct {
-	To   To              `toml:"to"`
-	From map[string]From `toml:"from"`
+	To       To              `toml:"to"`
+	From     map[string]From `toml:"from"`
+	Store    Store           `toml:"store"`
+	Events   Events          `toml:"events"`
+	Routes   []Route         `toml:"route"`
+	Naming   Naming          `toml:"naming"`
+	Cache    Cache           `toml:"cache"`
+	Backfill Backfill        `toml:"backfill"`
+}
+
+// Backfill is config of posting messages that are missed while disconnected
+type Backfill struct {
+	Disable     bool `toml:"disable"`
+	MaxMessages int  `toml:"max_messages"` // per channel, default: 500
+}
+
+// Cache is config of cache of channels, users and bots
+type Cache struct {
+	TTL Duration `toml:"ttl"` // default: "10m"
 }
 
 // To is token of aggregated slack
 type To struct {
 	Token string `toml:"token"`
+
+	Mode          string `toml:"mode"`           // "rtm" (default), "socket" or "events"
+	AppToken      string `toml:"app_token"`      // app-level token (xapp-) for socket mode
+	SigningSecret string `toml:"signing_secret"` // signing secret for events mode
+
+	CreateChannels  bool     `toml:"create_channels"`  // create missing aggregated channels at startup
+	PrivateChannels bool     `toml:"private_channels"` // create aggregated channels as private
+	Invite          []string `toml:"invite"`           // user ids that invited to created channels
+
+	PostInterval Duration `toml:"post_interval"` // min interval of posting per channel, default: "1s"
+	MaxRetries   int      `toml:"max_retries"`   // max retries of posting, default: 5
+
+	ThreadBroadcast bool `toml:"thread_broadcast"` // also send thread reply to channel if it is sent to channel in source
+	ReplyBroadcast  bool `toml:"reply_broadcast"`  // also send reply from aggregated slack to channel of source
+
+	ReverseReactions bool `toml:"reverse_reactions"` // mirror reactions in aggregated channel to source message
+
+	MaxFileSize int `toml:"max_file_size"` // max bytes of file that uploaded, larger file is posted as link. default: 10MiB
 }
 
 // From is token of source slack
 type From struct {
 	Token string `toml:"token"`
+
+	Mode          string `toml:"mode"`           // "rtm" (default), "socket" or "events"
+	AppToken      string `toml:"app_token"`      // app-level token (xapp-) for socket mode
+	SigningSecret string `toml:"signing_secret"` // signing secret for events mode
+
+	Channel   string `toml:"channel"`    // name of aggregated channel, overwrite [naming]
+	DMChannel string `toml:"dm_channel"` // name of private aggregated channel for DM and MPIM, overwrite [naming]
+	Private   *bool  `toml:"private"`    // create aggregated channel as private, overwrite [to]
+
+	Include      []string `toml:"include"`       // channel name patterns to aggregate (glob, or "/regexp/")
+	Exclude      []string `toml:"exclude"`       // channel name patterns not to aggregate
+	ChannelTypes []string `toml:"channel_types"` // "public", "private", "dm", "mpim", empty is all
+
+	EditMode   string `toml:"edit_mode"`   // "repost" (default), "update", "diff" or "both"
+	DeleteMode string `toml:"delete_mode"` // "repost" (default), "delete", "strike" or "replace"
+}
+
+// Events is config of HTTP receiver for Events API
+type Events struct {
+	Listen string `toml:"listen"` // e.g. ":3000"
+}
+
+// IngestOptions return options of ingestion for aggregated slack
+func (t To) IngestOptions() ingest.Options {
+	return ingest.Options{
+		Mode:          t.Mode,
+		AppToken:      t.AppToken,
+		SigningSecret: t.SigningSecret,
+	}
+}
+
+// IngestOptions return options of ingestion for source slack
+func (f From) IngestOptions(workspaceName string) ingest.Options {
+	return ingest.Options{
+		Name:          workspaceName,
+		Mode:          f.Mode,
+		AppToken:      f.AppToken,
+		SigningSecret: f.SigningSecret,
+	}
+}
+
+// Store is config of message log store
+type Store struct {
+	Type string `toml:"type"` // "memory" (default) or "bolt"
+	Path string `toml:"path"` // file path of bolt store
+
+	MaxAge        Duration `toml:"max_age"`     // e.g. "720h", empty is unlimited
+	MaxEntries    int      `toml:"max_entries"` // per workspace, 0 is unlimited
+	SweepInterval Duration `toml:"sweep_interval"`
+}
+
+// Duration is time.Duration that can unmarshal from string (e.g. "10m")
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parse duration string
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
 }
 
 // LoadConfig load config from configPath
 func LoadConfig(configPath string) error {
+	tomlConfig, err := load(configPath)
+	if err != nil {
+		return err
+	}
+
+	logStore, err := store.NewLogStore(tomlConfig.Store.Type, tomlConfig.Store.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create log store: %w", err)
+	}
+	store.SetLogStore(logStore)
+
+	apply(tomlConfig)
+
+	return nil
+}
+
+// ReloadConfig load config from configPath again, and apply it.
+// LogStore is not re-created, so changes of type and path in [store] require restarting.
+func ReloadConfig(configPath string) (*Diff, error) {
+	tomlConfig, err := load(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffConfig(GetConfig(), tomlConfig)
+	apply(tomlConfig)
+
+	return diff, nil
+}
+
+func load(configPath string) (Config, error) {
 	var tomlConfig Config
-	var err error
-	froms := map[string]string{}
-	fromApis := map[string]*slack.Client{}
 
-	b, err := fetch(configPath)
+	b, err := Fetch(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to load config from %s: %w", configPath, err)
+		return Config{}, fmt.Errorf("failed to load config from %s: %w", configPath, err)
 	}
 	if err := toml.Unmarshal(b, &tomlConfig); err != nil {
-		return fmt.Errorf("failed to unmarshal toml config: %w", err)
+		return Config{}, fmt.Errorf("failed to unmarshal toml config: %w", err)
+	}
+	for name, f := range tomlConfig.From {
+		if _, err := NewFilter(f); err != nil {
+			return Config{}, fmt.Errorf("invalid filter of %s: %w", name, err)
+		}
+		if err := validateEditMode(f.EditMode); err != nil {
+			return Config{}, fmt.Errorf("invalid edit_mode of %s: %w", name, err)
+		}
+		if err := validateDeleteMode(f.DeleteMode); err != nil {
+			return Config{}, fmt.Errorf("invalid delete_mode of %s: %w", name, err)
+		}
+	}
+	if err := validateEvents(tomlConfig); err != nil {
+		return Config{}, err
+	}
+	if _, err := compileRoutes(tomlConfig.Routes); err != nil {
+		return Config{}, err
+	}
+	if _, err := compileChannelTemplate(tomlConfig.Naming); err != nil {
+		return Config{}, err
+	}
+	if _, err := compileDMChannelTemplate(tomlConfig.Naming); err != nil {
+		return Config{}, err
+	}
+
+	return tomlConfig, nil
+}
+
+// validateEvents check listen address of Events API is set if some workspace use events mode
+func validateEvents(c Config) error {
+	if c.Events.Listen != "" {
+		return nil
+	}
+	if c.To.Mode == ingest.ModeEvents {
+		return fmt.Errorf("[events].listen is required because [to] use events mode")
 	}
+	for name, f := range c.From {
+		if f.Mode == ingest.ModeEvents {
+			return fmt.Errorf("[events].listen is required because %s use events mode", name)
+		}
+	}
+	return nil
+}
+
+func apply(tomlConfig Config) {
+	froms := map[string]string{}
+	fromApis := map[string]*slack.Client{}
+	old := GetConfig()
+
+	store.SetLogRetention(store.Retention{
+		MaxAge:        tomlConfig.Store.MaxAge.Duration,
+		MaxEntries:    tomlConfig.Store.MaxEntries,
+		SweepInterval: tomlConfig.Store.SweepInterval.Duration,
+	})
 
-	store.SetConfigToAPIToken(tomlConfig.To.Token)
+	outbound.Default.SetPolicy(tomlConfig.To.PostInterval.Duration, tomlConfig.To.MaxRetries)
 
+	if old.To.Token != tomlConfig.To.Token {
+		store.SetConfigToAPIToken(tomlConfig.To.Token)
+	}
+
+	newFilters := map[string]*Filter{}
 	for name, data := range tomlConfig.From {
+		// already validated in load
+		newFilters[name], _ = NewFilter(data)
+
 		froms[name] = data.Token
+		if o, ok := old.From[name]; ok && o.Token == data.Token {
+			// keep api instance of unaffected workspace
+			fromApis[name] = store.GetSlackAPIInstance(name)
+			continue
+		}
 		fromApis[name] = slack.New(data.Token)
 	}
 	store.SetConfigFromTokens(froms)
 	store.SetFromApis(fromApis)
 
-	return nil
+	// already validated in load
+	newRoutes, _ := compileRoutes(tomlConfig.Routes)
+	newChannelTemplate, _ := compileChannelTemplate(tomlConfig.Naming)
+	newDMChannelTemplate, _ := compileDMChannelTemplate(tomlConfig.Naming)
+
+	currentMu.Lock()
+	current = tomlConfig
+	filters = newFilters
+	routes = newRoutes
+	channelTemplate = newChannelTemplate
+	dmChannelTemplate = newDMChannelTemplate
+	currentMu.Unlock()
+}
+
+// GetConfig get loaded config
+func GetConfig() Config {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// GetTo get config of aggregated slack
+func GetTo() To {
+	return GetConfig().To
+}
+
+// GetFrom get config of source slack
+func GetFrom(workspaceName string) From {
+	return GetConfig().From[workspaceName]
 }
 
-func fetch(configPath string) ([]byte, error) {
+// Fetch get content of config from file path or URL
+func Fetch(configPath string) ([]byte, error) {
 	u, err := url.Parse(configPath)
 	if err != nil {
 		// this is file path!
@@ -83,8 +310,3 @@ func fetchHTTP(u *url.URL) ([]byte, error) {
 	defer resp.Body.Close()
 	return ioutil.ReadAll(resp.Body)
 }
-
-// GetToChannelName get channel name for aggregated message
-func GetToChannelName(workspaceName string) string {
-	return PrefixSlackChannel + strings.ToLower(workspaceName)
-}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/whywaita/aguri/pkg/ingest"
+)
+
+func TestValidateEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			name:   "no events mode",
+			config: Config{From: map[string]From{"team1": {Mode: ingest.ModeSocket}}},
+		},
+		{
+			name:    "events mode without listen",
+			config:  Config{From: map[string]From{"team1": {Mode: ingest.ModeEvents}}},
+			wantErr: true,
+		},
+		{
+			name:    "events mode of [to] without listen",
+			config:  Config{To: To{Mode: ingest.ModeEvents}},
+			wantErr: true,
+		},
+		{
+			name: "events mode with listen",
+			config: Config{
+				From:   map[string]From{"team1": {Mode: ingest.ModeEvents}},
+				Events: Events{Listen: ":3000"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEvents(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: want error %v, but %v", tt.wantErr, err)
+			}
+		})
+	}
+}
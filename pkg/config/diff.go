@@ -0,0 +1,49 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Diff is difference between old and new config
+type Diff struct {
+	Added   []string // name of workspaces that added in [from]
+	Removed []string // name of workspaces that removed from [from]
+	Changed []string // name of workspaces that changed in [from]
+
+	ToChanged    bool // [to] is changed
+	StoreChanged bool // type or path of [store] is changed
+}
+
+// IsEmpty return true if no difference
+func (d *Diff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && !d.ToChanged && !d.StoreChanged
+}
+
+// DiffConfig compare old and new config
+func DiffConfig(old, new Config) *Diff {
+	d := &Diff{}
+
+	for name, f := range new.From {
+		o, ok := old.From[name]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case !reflect.DeepEqual(o, f):
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range old.From {
+		if _, ok := new.From[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+
+	d.ToChanged = !reflect.DeepEqual(old.To, new.To)
+	d.StoreChanged = old.Store.Type != new.Store.Type || old.Store.Path != new.Store.Path
+
+	return d
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ChannelKindPublic is kind of public channel
+	ChannelKindPublic = "public"
+	// ChannelKindPrivate is kind of private channel
+	ChannelKindPrivate = "private"
+	// ChannelKindDM is kind of direct message
+	ChannelKindDM = "dm"
+	// ChannelKindMPIM is kind of multi-person direct message
+	ChannelKindMPIM = "mpim"
+)
+
+var (
+	filters = map[string]*Filter{} // key: workspace name
+)
+
+// Filter is filter of source channels
+type Filter struct {
+	include      []pattern
+	exclude      []pattern
+	channelKinds []string
+}
+
+type pattern struct {
+	raw string
+	re  *regexp.Regexp // nil if glob
+}
+
+func (p pattern) match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	ok, _ := path.Match(p.raw, name)
+	return ok
+}
+
+func compilePattern(raw string) (pattern, error) {
+	// "/regexp/" is regular expression, others are glob (e.g. "incident-*")
+	if len(raw) >= 2 && strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/") {
+		re, err := regexp.Compile(raw[1 : len(raw)-1])
+		if err != nil {
+			return pattern{}, fmt.Errorf("failed to compile regexp %s: %w", raw, err)
+		}
+		return pattern{raw: raw, re: re}, nil
+	}
+	if _, err := path.Match(raw, ""); err != nil {
+		return pattern{}, fmt.Errorf("invalid glob %s: %w", raw, err)
+	}
+
+	return pattern{raw: raw}, nil
+}
+
+// NewFilter create Filter from config of source slack
+func NewFilter(f From) (*Filter, error) {
+	filter := &Filter{}
+
+	for _, raw := range f.Include {
+		p, err := compilePattern(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include: %w", err)
+		}
+		filter.include = append(filter.include, p)
+	}
+	for _, raw := range f.Exclude {
+		p, err := compilePattern(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude: %w", err)
+		}
+		filter.exclude = append(filter.exclude, p)
+	}
+	for _, kind := range f.ChannelTypes {
+		switch kind {
+		case ChannelKindPublic, ChannelKindPrivate, ChannelKindDM, ChannelKindMPIM:
+			filter.channelKinds = append(filter.channelKinds, kind)
+		default:
+			return nil, fmt.Errorf("invalid channel_types: %s", kind)
+		}
+	}
+
+	return filter, nil
+}
+
+// IsEmpty return true if filter allow all channels
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.include) == 0 && len(f.exclude) == 0 && len(f.channelKinds) == 0)
+}
+
+// Allow return true if message in channel must be aggregated
+func (f *Filter) Allow(kind, channelName string) bool {
+	if f.IsEmpty() {
+		return true
+	}
+
+	if len(f.channelKinds) != 0 && !contains(f.channelKinds, kind) {
+		return false
+	}
+	for _, p := range f.exclude {
+		if p.match(channelName) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.match(channelName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String return readable format of filter
+func (f *Filter) String() string {
+	if f.IsEmpty() {
+		return "no filter (all channels are aggregated)"
+	}
+
+	join := func(ps []pattern) string {
+		var raws []string
+		for _, p := range ps {
+			raws = append(raws, p.raw)
+		}
+		if len(raws) == 0 {
+			return "(none)"
+		}
+		return strings.Join(raws, ", ")
+	}
+	kinds := strings.Join(f.channelKinds, ", ")
+	if kinds == "" {
+		kinds = "(all)"
+	}
+
+	return fmt.Sprintf("include: %s\nexclude: %s\nchannel types: %s", join(f.include), join(f.exclude), kinds)
+}
+
+// GetFilter get Filter of workspace
+func GetFilter(workspaceName string) *Filter {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return filters[workspaceName]
+}
+
+func contains(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package config
+
+import "testing"
+
+func TestFilterAllow(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        From
+		kind        string
+		channelName string
+		want        bool
+	}{
+		{name: "no filter", from: From{}, kind: ChannelKindPublic, channelName: "general", want: true},
+		{name: "glob is matched", from: From{Include: []string{"incident-*"}}, kind: ChannelKindPublic, channelName: "incident-123", want: true},
+		{name: "glob is not matched", from: From{Include: []string{"incident-*"}}, kind: ChannelKindPublic, channelName: "general", want: false},
+		{name: "glob of single character", from: From{Include: []string{"dev-?"}}, kind: ChannelKindPublic, channelName: "dev-a", want: true},
+		{name: "glob match whole name", from: From{Include: []string{"dev"}}, kind: ChannelKindPublic, channelName: "dev-a", want: false},
+		{name: "regexp is matched", from: From{Include: []string{"/^team-(a|b)$/"}}, kind: ChannelKindPublic, channelName: "team-b", want: true},
+		{name: "regexp is not matched", from: From{Include: []string{"/^team-(a|b)$/"}}, kind: ChannelKindPublic, channelName: "team-c", want: false},
+		{name: "regexp match part of name", from: From{Include: []string{"/ops/"}}, kind: ChannelKindPublic, channelName: "dev-ops-alert", want: true},
+		{name: "exclude only", from: From{Exclude: []string{"random"}}, kind: ChannelKindPublic, channelName: "general", want: true},
+		{name: "excluded", from: From{Exclude: []string{"random"}}, kind: ChannelKindPublic, channelName: "random", want: false},
+		{name: "exclude is prior to include", from: From{Include: []string{"*"}, Exclude: []string{"/^secret-/"}}, kind: ChannelKindPublic, channelName: "secret-a", want: false},
+		{name: "kind is matched", from: From{ChannelTypes: []string{"public", "private"}}, kind: ChannelKindPrivate, channelName: "general", want: true},
+		{name: "kind is not matched", from: From{ChannelTypes: []string{"public"}}, kind: ChannelKindDM, channelName: "alice", want: false},
+		{name: "kind and pattern", from: From{Include: []string{"incident-*"}, ChannelTypes: []string{"private"}}, kind: ChannelKindPublic, channelName: "incident-1", want: false},
+		{name: "include doesn't match DM", from: From{Include: []string{"incident-*"}}, kind: ChannelKindDM, channelName: "alice", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.from)
+			if err != nil {
+				t.Fatalf("failed to create filter: %+v", err)
+			}
+			if got := f.Allow(tt.kind, tt.channelName); got != tt.want {
+				t.Errorf("want %v, but %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      From
+		wantErr   bool
+		wantEmpty bool
+	}{
+		{name: "empty", from: From{}, wantEmpty: true},
+		{name: "valid", from: From{Include: []string{"dev-*", "/^ops$/"}, Exclude: []string{"dev-test"}, ChannelTypes: []string{"public", "private", "dm", "mpim"}}},
+		{name: "invalid glob", from: From{Include: []string{"dev-["}}, wantErr: true},
+		{name: "invalid regexp", from: From{Exclude: []string{"/(ops/"}}, wantErr: true},
+		{name: "invalid kind", from: From{ChannelTypes: []string{"group"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.from)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && f.IsEmpty() != tt.wantEmpty {
+				t.Errorf("IsEmpty: want %v, but %v", tt.wantEmpty, f.IsEmpty())
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import "fmt"
+
+const (
+	// EditModeRepost is mode that post new message that contains original and edited text
+	EditModeRepost = "repost"
+	// EditModeUpdate is mode that update aggregated message
+	EditModeUpdate = "update"
+	// EditModeDiff is mode that post diff to thread of aggregated message
+	EditModeDiff = "diff"
+	// EditModeBoth is mode that update aggregated message and post diff to thread
+	EditModeBoth = "both"
+)
+
+func validateEditMode(mode string) error {
+	switch mode {
+	case "", EditModeRepost, EditModeUpdate, EditModeDiff, EditModeBoth:
+		return nil
+	default:
+		return fmt.Errorf("unsupported mode: %s", mode)
+	}
+}
+
+// GetEditMode get mode of mirroring edited message in workspace
+func GetEditMode(workspaceName string) string {
+	mode := GetFrom(workspaceName).EditMode
+	if mode == "" {
+		return EditModeRepost
+	}
+	return mode
+}
+
+const (
+	// DeleteModeRepost is mode that post new message that contains original text
+	DeleteModeRepost = "repost"
+	// DeleteModeDelete is mode that delete aggregated message
+	DeleteModeDelete = "delete"
+	// DeleteModeStrike is mode that strike through aggregated message
+	DeleteModeStrike = "strike"
+	// DeleteModeReplace is mode that replace aggregated message with "[deleted]"
+	DeleteModeReplace = "replace"
+)
+
+func validateDeleteMode(mode string) error {
+	switch mode {
+	case "", DeleteModeRepost, DeleteModeDelete, DeleteModeStrike, DeleteModeReplace:
+		return nil
+	default:
+		return fmt.Errorf("unsupported mode: %s", mode)
+	}
+}
+
+// GetDeleteMode get mode of mirroring deleted message in workspace
+func GetDeleteMode(workspaceName string) string {
+	mode := GetFrom(workspaceName).DeleteMode
+	if mode == "" {
+		return DeleteModeRepost
+	}
+	return mode
+}
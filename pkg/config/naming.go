@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	// DefaultChannelTemplate is default template of aggregated channel name
+	DefaultChannelTemplate = "{{.Prefix}}{{.Workspace}}"
+
+	// maxChannelNameLength is max length of channel name in Slack
+	maxChannelNameLength = 80
+)
+
+var (
+	// characters that Slack do not allow in channel name
+	reInvalidChannelChars = regexp.MustCompile(`[^a-z0-9_\-]+`)
+
+	channelTemplate = template.Must(template.New("channel").Parse(DefaultChannelTemplate))
+	// dmChannelTemplate is nil if DM and MPIM are aggregated to same channel as other channels
+	dmChannelTemplate *template.Template
+)
+
+// Naming is config of aggregated channel name
+type Naming struct {
+	Prefix   string `toml:"prefix"`   // default: "aggr-"
+	Template string `toml:"template"` // default: "{{.Prefix}}{{.Workspace}}"
+
+	DMTemplate string `toml:"dm_template"` // name of private channel for DM and MPIM (e.g. "{{.Prefix}}{{.Workspace}}-dm"), empty is same as template
+}
+
+type namingData struct {
+	Prefix    string
+	Workspace string
+}
+
+func compileChannelTemplate(n Naming) (*template.Template, error) {
+	text := n.Template
+	if text == "" {
+		text = DefaultChannelTemplate
+	}
+	return compileTemplate("channel", text)
+}
+
+func compileDMChannelTemplate(n Naming) (*template.Template, error) {
+	if n.DMTemplate == "" {
+		return nil, nil
+	}
+	return compileTemplate("dm_channel", n.DMTemplate)
+}
+
+func compileTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template of %s name: %w", name, err)
+	}
+	if err := t.Execute(&bytes.Buffer{}, namingData{}); err != nil {
+		return nil, fmt.Errorf("failed to execute template of %s name: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// SanitizeChannelName convert name to format that Slack allow in channel name
+func SanitizeChannelName(name string) string {
+	name = strings.ToLower(strings.TrimPrefix(name, "#"))
+	name = reInvalidChannelChars.ReplaceAllString(name, "-")
+	if len(name) > maxChannelNameLength {
+		name = name[:maxChannelNameLength]
+	}
+	return name
+}
+
+// GetToChannelName get channel name for aggregated message
+func GetToChannelName(workspaceName string) string {
+	c := GetConfig()
+	if f, ok := c.From[workspaceName]; ok && f.Channel != "" {
+		// explicit name
+		return SanitizeChannelName(f.Channel)
+	}
+
+	prefix := c.Naming.Prefix
+	if prefix == "" {
+		prefix = PrefixSlackChannel
+	}
+
+	currentMu.RLock()
+	t := channelTemplate
+	currentMu.RUnlock()
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, namingData{Prefix: prefix, Workspace: workspaceName}); err != nil {
+		// already validated in load
+		return SanitizeChannelName(prefix + workspaceName)
+	}
+
+	return SanitizeChannelName(buf.String())
+}
+
+// GetDMChannelName get channel name for aggregated DM and MPIM, return empty if it is not configured
+func GetDMChannelName(workspaceName string) string {
+	c := GetConfig()
+	if f, ok := c.From[workspaceName]; ok && f.DMChannel != "" {
+		// explicit name
+		return SanitizeChannelName(f.DMChannel)
+	}
+
+	currentMu.RLock()
+	t := dmChannelTemplate
+	currentMu.RUnlock()
+	if t == nil {
+		return ""
+	}
+
+	prefix := c.Naming.Prefix
+	if prefix == "" {
+		prefix = PrefixSlackChannel
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, namingData{Prefix: prefix, Workspace: workspaceName}); err != nil {
+		// already validated in load
+		return ""
+	}
+
+	return SanitizeChannelName(buf.String())
+}
@@ -0,0 +1,116 @@
+package config
+
+import "testing"
+
+func TestSanitizeChannelName(t *testing.T) {
+	long := "aggr-"
+	for len(long) < 100 {
+		long += "x"
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "aggr-team1", want: "aggr-team1"},
+		{name: "Aggr-Team1", want: "aggr-team1"},
+		{name: "#aggr-team1", want: "aggr-team1"},
+		{name: "aggr team one", want: "aggr-team-one"},
+		{name: "aggr_team.one!!", want: "aggr_team-one-"},
+		{name: long, want: long[:maxChannelNameLength]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeChannelName(tt.name); got != tt.want {
+				t.Errorf("want %s, but %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChannelName(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+		wantDM string
+	}{
+		{
+			name:   "default",
+			config: Config{From: map[string]From{"team1": {}}},
+			want:   "aggr-team1",
+			wantDM: "",
+		},
+		{
+			name: "prefix and templates",
+			config: Config{
+				From:   map[string]From{"team1": {}},
+				Naming: Naming{Prefix: "mirror-", Template: "{{.Workspace}}-{{.Prefix}}all", DMTemplate: "{{.Prefix}}{{.Workspace}}-dm"},
+			},
+			want:   "team1-mirror-all",
+			wantDM: "mirror-team1-dm",
+		},
+		{
+			name: "template output is sanitized",
+			config: Config{
+				From:   map[string]From{"Team One": {}},
+				Naming: Naming{DMTemplate: "{{.Workspace}} DM"},
+			},
+			want:   "aggr-team-one",
+			wantDM: "team-one-dm",
+		},
+		{
+			name: "explicit name overwrite templates",
+			config: Config{
+				From:   map[string]From{"team1": {Channel: "Team One", DMChannel: "#Team-One-DM"}},
+				Naming: Naming{DMTemplate: "{{.Prefix}}{{.Workspace}}-dm"},
+			},
+			want:   "team-one",
+			wantDM: "team-one-dm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apply(tt.config)
+			defer apply(Config{})
+
+			var workspace string
+			for name := range tt.config.From {
+				workspace = name
+			}
+			if got := GetToChannelName(workspace); got != tt.want {
+				t.Errorf("unexpected channel name: want %s, but %s", tt.want, got)
+			}
+			if got := GetDMChannelName(workspace); got != tt.wantDM {
+				t.Errorf("unexpected channel name of DM: want %s, but %s", tt.wantDM, got)
+			}
+		})
+	}
+}
+
+func TestCompileTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		naming  Naming
+		wantErr bool
+	}{
+		{name: "default", naming: Naming{}},
+		{name: "valid", naming: Naming{Template: "{{.Prefix}}{{.Workspace}}-all", DMTemplate: "{{.Workspace}}-dm"}},
+		{name: "parse error", naming: Naming{Template: "{{.Prefix"}, wantErr: true},
+		{name: "unknown field", naming: Naming{DMTemplate: "{{.Channel}}"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileChannelTemplate(tt.naming)
+			if err == nil {
+				_, err = compileDMChannelTemplate(tt.naming)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
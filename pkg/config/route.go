@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+)
+
+var (
+	routes []*compiledRoute
+)
+
+// Route is rule of routing source channels to destination channel
+type Route struct {
+	Workspaces   []string `toml:"workspaces"`    // name of source workspaces, empty or "*" is all
+	Channels     []string `toml:"channels"`      // channel name patterns (glob, or "/regexp/"), empty is all
+	ChannelTypes []string `toml:"channel_types"` // "public", "private", "dm", "mpim", empty is all
+	To           string   `toml:"to"`            // name of destination channel
+}
+
+type compiledRoute struct {
+	workspaces []string
+	filter     *Filter
+	to         string
+}
+
+func compileRoutes(rs []Route) ([]*compiledRoute, error) {
+	var compiled []*compiledRoute
+	for i, r := range rs {
+		to := SanitizeChannelName(r.To)
+		if to == "" {
+			return nil, fmt.Errorf("to is required in route[%d]", i)
+		}
+		filter, err := NewFilter(From{Include: r.Channels, ChannelTypes: r.ChannelTypes})
+		if err != nil {
+			return nil, fmt.Errorf("invalid route[%d]: %w", i, err)
+		}
+		compiled = append(compiled, &compiledRoute{
+			workspaces: r.Workspaces,
+			filter:     filter,
+			to:         to,
+		})
+	}
+
+	return compiled, nil
+}
+
+func (r *compiledRoute) match(workspaceName, kind, channelName string) bool {
+	if len(r.workspaces) != 0 && !contains(r.workspaces, "*") && !contains(r.workspaces, workspaceName) {
+		return false
+	}
+	return r.filter.Allow(kind, channelName)
+}
+
+// mayMatchDM check route may match DM or MPIM.
+// route that has only channel patterns is not regarded, because name of DM is names of participants.
+func (r *compiledRoute) mayMatchDM() bool {
+	if len(r.filter.channelKinds) == 0 {
+		return len(r.filter.include) == 0
+	}
+	return contains(r.filter.channelKinds, ChannelKindDM) || contains(r.filter.channelKinds, ChannelKindMPIM)
+}
+
+// ResolveDestination get name of destination channel for message in source channel.
+// First matched route is used, and per-workspace channel (or channel for DM and MPIM) is used if no route is matched.
+func ResolveDestination(workspaceName, kind, channelName string) string {
+	currentMu.RLock()
+	rs := routes
+	currentMu.RUnlock()
+
+	for _, r := range rs {
+		if r.match(workspaceName, kind, channelName) {
+			return r.to
+		}
+	}
+
+	if kind == ChannelKindDM || kind == ChannelKindMPIM {
+		if name := GetDMChannelName(workspaceName); name != "" {
+			return name
+		}
+	}
+
+	return GetToChannelName(workspaceName)
+}
+
+// GetRouteDestinations get names of destination channel of routes
+func GetRouteDestinations() []string {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+
+	var names []string
+	for _, r := range routes {
+		if !contains(names, r.to) {
+			names = append(names, r.to)
+		}
+	}
+	return names
+}
+
+// GetDMRouteDestinations get names of destination channel of routes that may match DM or MPIM,
+// these channels must be private
+func GetDMRouteDestinations() []string {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+
+	var names []string
+	for _, r := range routes {
+		if r.mayMatchDM() && !contains(names, r.to) {
+			names = append(names, r.to)
+		}
+	}
+	return names
+}
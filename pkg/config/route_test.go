@@ -0,0 +1,83 @@
+package config
+
+import "testing"
+
+func TestRouteMayMatchDM(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+		want  bool
+	}{
+		{name: "all channels", route: Route{To: "aggr-all"}, want: true},
+		{name: "dm", route: Route{ChannelTypes: []string{"dm"}, To: "aggr-dm"}, want: true},
+		{name: "mpim and public", route: Route{ChannelTypes: []string{"public", "mpim"}, To: "aggr-mixed"}, want: true},
+		{name: "public only", route: Route{ChannelTypes: []string{"public", "private"}, To: "aggr-channels"}, want: false},
+		{name: "channel patterns only", route: Route{Channels: []string{"incident-*"}, To: "aggr-incidents"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := compileRoutes([]Route{tt.route})
+			if err != nil {
+				t.Fatalf("failed to compile route: %+v", err)
+			}
+			if got := compiled[0].mayMatchDM(); got != tt.want {
+				t.Errorf("mayMatchDM: want %v, but %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveDestination(t *testing.T) {
+	config := Config{
+		From: map[string]From{
+			"team1": {},
+			"team2": {Channel: "team2-all"},
+		},
+		Naming: Naming{DMTemplate: "{{.Prefix}}{{.Workspace}}-dm"},
+		Routes: []Route{
+			{Channels: []string{"incident-*"}, To: "aggr-incidents"},
+			{Workspaces: []string{"team1"}, Channels: []string{"/^ops-/"}, To: "aggr-team1-ops"},
+			{Workspaces: []string{"*"}, Channels: []string{"ops-*"}, To: "aggr-ops"},
+			{Workspaces: []string{"team2"}, ChannelTypes: []string{"mpim"}, To: "aggr-team2-mpim"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		workspace   string
+		kind        string
+		channelName string
+		want        string
+	}{
+		{name: "route of all workspaces", workspace: "team2", kind: ChannelKindPublic, channelName: "incident-1", want: "aggr-incidents"},
+		{name: "first matched route is used", workspace: "team1", kind: ChannelKindPublic, channelName: "ops-alert", want: "aggr-team1-ops"},
+		{name: "route of other workspace is skipped", workspace: "team2", kind: ChannelKindPublic, channelName: "ops-alert", want: "aggr-ops"},
+		{name: "no route is matched", workspace: "team1", kind: ChannelKindPublic, channelName: "general", want: "aggr-team1"},
+		{name: "explicit channel of workspace", workspace: "team2", kind: ChannelKindPrivate, channelName: "general", want: "team2-all"},
+		{name: "channel for DM", workspace: "team1", kind: ChannelKindDM, channelName: "alice", want: "aggr-team1-dm"},
+		{name: "route is prior to channel for DM", workspace: "team2", kind: ChannelKindMPIM, channelName: "mpdm-alice--bob-1", want: "aggr-team2-mpim"},
+		{name: "channel for DM of other workspace", workspace: "team2", kind: ChannelKindDM, channelName: "alice", want: "aggr-team2-dm"},
+	}
+
+	apply(config)
+	defer apply(Config{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveDestination(tt.workspace, tt.kind, tt.channelName); got != tt.want {
+				t.Errorf("want %s, but %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveDestinationWithoutDMChannel(t *testing.T) {
+	apply(Config{From: map[string]From{"team1": {}}})
+	defer apply(Config{})
+
+	// DM is aggregated to channel of workspace if dm_template is not set
+	if got := ResolveDestination("team1", ChannelKindDM, "alice"); got != "aggr-team1" {
+		t.Errorf("want aggr-team1, but %s", got)
+	}
+}
@@ -0,0 +1,43 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// ConvertCallbackEvent translate payload of Events API (event_callback) to slack.RTMEvent
+func ConvertCallbackEvent(payload json.RawMessage) (*slack.RTMEvent, error) {
+	var cb slackevents.EventsAPICallbackEvent
+	if err := json.Unmarshal(payload, &cb); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal callback event: %w", err)
+	}
+	if cb.InnerEvent == nil {
+		return nil, fmt.Errorf("inner event is not found")
+	}
+
+	return ConvertInnerEvent(*cb.InnerEvent)
+}
+
+// ConvertInnerEvent translate inner event of Events API to slack.RTMEvent.
+// Inner events have same format of RTM events, so use slack.EventMapping.
+func ConvertInnerEvent(inner json.RawMessage) (*slack.RTMEvent, error) {
+	var e slack.Event
+	if err := json.Unmarshal(inner, &e); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal inner event: %w", err)
+	}
+
+	v, ok := slack.EventMapping[e.Type]
+	if !ok {
+		return nil, fmt.Errorf("unmapped event type: %s", e.Type)
+	}
+	data := reflect.New(reflect.TypeOf(v)).Interface()
+	if err := json.Unmarshal(inner, data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event (type: %s): %w", e.Type, err)
+	}
+
+	return &slack.RTMEvent{Type: e.Type, Data: data}, nil
+}
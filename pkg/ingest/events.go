@@ -0,0 +1,182 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+const (
+	// PrefixEventsPath is prefix of URL path that receive Events API
+	PrefixEventsPath = "/slack/events/"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// DefaultEventsServer is EventsServer used by Stream
+var DefaultEventsServer = NewEventsServer()
+
+// EventsPath return URL path that receive Events API of workspace
+func EventsPath(workspace string) string {
+	return PrefixEventsPath + workspace
+}
+
+// EventsServer is receiver of Events API via HTTP
+type EventsServer struct {
+	mu        sync.RWMutex
+	receivers map[string]*eventsReceiver // key: URL path
+}
+
+type eventsReceiver struct {
+	ctx           context.Context
+	signingSecret string
+
+	mu     sync.RWMutex
+	closed bool
+	out    chan slack.RTMEvent
+}
+
+// NewEventsServer create EventsServer
+func NewEventsServer() *EventsServer {
+	return &EventsServer{
+		receivers: map[string]*eventsReceiver{},
+	}
+}
+
+// Register start to receive Events API on path, and return channel of events.
+// The channel is closed and path is unregistered when ctx is done.
+func (s *EventsServer) Register(ctx context.Context, path, signingSecret string) <-chan slack.RTMEvent {
+	r := &eventsReceiver{
+		ctx:           ctx,
+		signingSecret: signingSecret,
+		out:           make(chan slack.RTMEvent, eventBufferSize),
+	}
+
+	s.mu.Lock()
+	s.receivers[path] = r
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		if s.receivers[path] == r {
+			delete(s.receivers, path)
+		}
+		s.mu.Unlock()
+		r.close()
+	}()
+
+	r.out <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+	return r.out
+}
+
+// ServeHTTP verify signature and dispatch events to receiver of path
+func (s *EventsServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.RLock()
+	r, ok := s.receivers[req.URL.Path]
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sv, err := slack.NewSecretsVerifier(req.Header, r.signingSecret)
+	if err != nil {
+		// headers are missing or timestamp is too old
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if _, err := sv.Write(body); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := sv.Ensure(); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var outer slackevents.EventsAPIEvent
+	if err := json.Unmarshal(body, &outer); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch outer.Type {
+	case slackevents.URLVerification:
+		var v slackevents.ChallengeResponse
+		if err := json.Unmarshal(body, &v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(v.Challenge))
+	case slackevents.CallbackEvent:
+		// response before handle event, Slack require response in 3 seconds
+		w.WriteHeader(http.StatusOK)
+
+		msg, err := ConvertCallbackEvent(body)
+		if err != nil {
+			msg = &slack.RTMEvent{Type: "incoming_error", Data: &slack.IncomingEventError{ErrorObj: err}}
+		}
+		r.dispatch(*msg)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (r *eventsReceiver) dispatch(msg slack.RTMEvent) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		// receiver is unregistered while handling request
+		logrus.Debugf("receiver is already closed, drop event: %s", msg.Type)
+		return
+	}
+	select {
+	case r.out <- msg:
+	case <-r.ctx.Done():
+	}
+}
+
+func (r *eventsReceiver) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	close(r.out)
+}
+
+// ListenAndServe serve EventsServer on addr until ctx is done
+func (s *EventsServer) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: s,
+	}
+
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(sctx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
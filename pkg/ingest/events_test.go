@@ -0,0 +1,110 @@
+package ingest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const testSigningSecret = "test-signing-secret"
+
+func signRequest(req *http.Request, secret, body string, at time.Time) {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestEventsServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		at       time.Time
+		wantCode int
+		wantMsg  bool
+	}{
+		{
+			name:     "valid",
+			secret:   testSigningSecret,
+			at:       time.Now(),
+			wantCode: http.StatusOK,
+			wantMsg:  true,
+		},
+		{
+			name:     "bad signature",
+			secret:   "invalid-secret",
+			at:       time.Now(),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "stale timestamp",
+			secret:   testSigningSecret,
+			at:       time.Now().Add(-10 * time.Minute),
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			s := NewEventsServer()
+			out := s.Register(ctx, EventsPath("team1"), testSigningSecret)
+			if msg := <-out; msg.Type != "connected" {
+				t.Fatalf("first event must be connected, but %s", msg.Type)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, EventsPath("team1"), strings.NewReader(testEventCallback))
+			signRequest(req, tt.secret, testEventCallback, tt.at)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("unexpected status code: want %d, but %d", tt.wantCode, rec.Code)
+			}
+
+			select {
+			case msg := <-out:
+				if !tt.wantMsg {
+					t.Fatalf("must not dispatch event, but %s", msg.Type)
+				}
+				ev, ok := msg.Data.(*slack.MessageEvent)
+				if !ok {
+					t.Fatalf("unexpected event: %+v", msg)
+				}
+				if ev.Channel != "C01" || ev.Text != "hello aguri" {
+					t.Errorf("unexpected message event: %+v", ev.Msg)
+				}
+			default:
+				if tt.wantMsg {
+					t.Errorf("event is not dispatched")
+				}
+			}
+		})
+	}
+}
+
+func TestEventsServerNotRegistered(t *testing.T) {
+	s := NewEventsServer()
+
+	req := httptest.NewRequest(http.MethodPost, EventsPath("unknown"), strings.NewReader(testEventCallback))
+	signRequest(req, testSigningSecret, testEventCallback, time.Now())
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unexpected status code: want %d, but %d", http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// ModeRTM is mode of ingestion that use legacy RTM API
+	ModeRTM = "rtm"
+	// ModeSocket is mode of ingestion that use Socket Mode
+	ModeSocket = "socket"
+	// ModeEvents is mode of ingestion that receive Events API via HTTP
+	ModeEvents = "events"
+
+	eventBufferSize = 50
+)
+
+// Options is options of ingestion
+type Options struct {
+	Name          string // name of workspace
+	Mode          string // "rtm" (default), "socket" or "events"
+	AppToken      string // app-level token (xapp-), required in socket mode
+	SigningSecret string // signing secret, required in events mode
+}
+
+// Stream start to receive events from api, and return channel of events.
+// All events are translated to slack.RTMEvent, so handlers can process it regardless of mode.
+// The channel is closed when ctx is done.
+func Stream(ctx context.Context, api *slack.Client, opts Options) (<-chan slack.RTMEvent, error) {
+	switch opts.Mode {
+	case "", ModeRTM:
+		return streamRTM(ctx, api), nil
+	case ModeSocket:
+		if opts.AppToken == "" {
+			return nil, fmt.Errorf("app_token is required in socket mode (workspace: %s)", opts.Name)
+		}
+		return streamSocketMode(ctx, opts.AppToken), nil
+	case ModeEvents:
+		if opts.SigningSecret == "" {
+			return nil, fmt.Errorf("signing_secret is required in events mode (workspace: %s)", opts.Name)
+		}
+		return DefaultEventsServer.Register(ctx, EventsPath(opts.Name), opts.SigningSecret), nil
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s (workspace: %s)", opts.Mode, opts.Name)
+	}
+}
+
+func streamRTM(ctx context.Context, api *slack.Client) <-chan slack.RTMEvent {
+	out := make(chan slack.RTMEvent, eventBufferSize)
+
+	rtm := api.NewRTM(slack.RTMOptionUseStart(false))
+	go rtm.ManageConnection()
+	go func() {
+		defer close(out)
+		defer rtm.Disconnect()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-rtm.IncomingEvents:
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,115 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// slackAPIURL is URL of Slack API that open connection of socket mode, it is replaced in tests
+var slackAPIURL = slack.APIURL
+
+func streamSocketMode(ctx context.Context, appToken string) <-chan slack.RTMEvent {
+	out := make(chan slack.RTMEvent, eventBufferSize)
+
+	// socket mode client use app-level token only for apps.connections.open
+	smc := socketmode.New(slack.New("", slack.OptionAppLevelToken(appToken), slack.OptionAPIURL(slackAPIURL)))
+
+	// runCtx is canceled when RunContext is finished (e.g. failed to reconnect),
+	// then out is closed and caller can notice end of stream
+	runCtx, cancel := context.WithCancel(ctx)
+	runDone := make(chan struct{})
+	var runErr error
+	go func() {
+		defer cancel()
+		defer close(runDone)
+		runErr = smc.RunContext(runCtx)
+	}()
+	go func() {
+		defer close(out)
+		defer func() {
+			// RunContext block sending events, so drain it until finished
+			go func() {
+				for {
+					select {
+					case <-smc.Events:
+					case <-runDone:
+						return
+					}
+				}
+			}()
+		}()
+
+		for {
+			select {
+			case <-runCtx.Done():
+				select {
+				case <-runDone:
+					if runErr != nil && ctx.Err() == nil {
+						send(ctx, out, slack.RTMEvent{Type: "connection_error", Data: &slack.ConnectionErrorEvent{ErrorObj: runErr}})
+					}
+				default:
+					// ctx is done
+				}
+				return
+			case evt := <-smc.Events:
+				msg, ok := convertSocketModeEvent(smc, evt)
+				if !ok {
+					continue
+				}
+				if !send(ctx, out, *msg) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+func convertSocketModeEvent(smc *socketmode.Client, evt socketmode.Event) (*slack.RTMEvent, bool) {
+	switch evt.Type {
+	case socketmode.EventTypeConnecting:
+		return &slack.RTMEvent{Type: "connecting", Data: &slack.ConnectingEvent{}}, true
+	case socketmode.EventTypeConnected:
+		return &slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}, true
+	case socketmode.EventTypeHello:
+		return &slack.RTMEvent{Type: "hello", Data: &slack.HelloEvent{}}, true
+	case socketmode.EventTypeDisconnect:
+		return &slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: false}}, true
+	case socketmode.EventTypeInvalidAuth:
+		return &slack.RTMEvent{Type: "invalid_auth", Data: &slack.InvalidAuthEvent{}}, true
+	case socketmode.EventTypeConnectionError:
+		err, _ := evt.Data.(error)
+		return &slack.RTMEvent{Type: "connection_error", Data: &slack.ConnectionErrorEvent{ErrorObj: err}}, true
+	case socketmode.EventTypeEventsAPI:
+		if evt.Request == nil {
+			return nil, false
+		}
+		smc.Ack(*evt.Request)
+		msg, err := ConvertCallbackEvent(evt.Request.Payload)
+		if err != nil {
+			return &slack.RTMEvent{Type: "incoming_error", Data: &slack.IncomingEventError{ErrorObj: err}}, true
+		}
+		return msg, true
+	case socketmode.EventTypeInteractive, socketmode.EventTypeSlashCommand:
+		// aguri is not support interactive features, only ack
+		if evt.Request != nil {
+			smc.Ack(*evt.Request)
+		}
+		return nil, false
+	default:
+		return &slack.RTMEvent{Type: "incoming_error", Data: &slack.IncomingEventError{ErrorObj: fmt.Errorf("unexpected socket mode event: %s", evt.Type)}}, true
+	}
+}
+
+func send(ctx context.Context, out chan<- slack.RTMEvent, msg slack.RTMEvent) bool {
+	select {
+	case out <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,198 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+const testEventCallback = `{
+	"type": "event_callback",
+	"team_id": "T01",
+	"event": {
+		"type": "message",
+		"channel": "C01",
+		"user": "U01",
+		"text": "hello aguri",
+		"ts": "1600000000.000100"
+	}
+}`
+
+// fakeSocketModeServer is fake of apps.connections.open and websocket of Socket Mode
+type fakeSocketModeServer struct {
+	*httptest.Server
+
+	envelopes []socketmode.Request
+	acks      chan socketmode.Response
+	openError string // error of apps.connections.open, succeed if empty
+}
+
+func newFakeSocketModeServer(t *testing.T, openError string, envelopes ...socketmode.Request) *fakeSocketModeServer {
+	t.Helper()
+
+	f := &fakeSocketModeServer{
+		envelopes: envelopes,
+		acks:      make(chan socketmode.Response, len(envelopes)),
+		openError: openError,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer xapp-test" {
+			t.Errorf("unexpected authorization header: %s", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if f.openError != "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": f.openError})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":  true,
+			"url": "ws" + strings.TrimPrefix(f.URL, "http") + "/link",
+		})
+	})
+	mux.HandleFunc("/link", f.serveWebsocket(t))
+	f.Server = httptest.NewServer(mux)
+
+	return f
+}
+
+func (f *fakeSocketModeServer) serveWebsocket(t *testing.T) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		// client of Socket Mode send origin of Slack
+		CheckOrigin: func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "https://api.slack.com"
+		},
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade websocket: %+v", err)
+			return
+		}
+		defer conn.Close()
+
+		if err := conn.WriteJSON(socketmode.Request{Type: socketmode.RequestTypeHello}); err != nil {
+			t.Errorf("failed to write hello: %+v", err)
+			return
+		}
+		for _, e := range f.envelopes {
+			if err := conn.WriteJSON(e); err != nil {
+				t.Errorf("failed to write envelope: %+v", err)
+				return
+			}
+		}
+
+		for {
+			var res socketmode.Response
+			if err := conn.ReadJSON(&res); err != nil {
+				// client is closed
+				return
+			}
+			f.acks <- res
+		}
+	}
+}
+
+func TestStreamSocketMode(t *testing.T) {
+	f := newFakeSocketModeServer(t, "", socketmode.Request{
+		Type:       socketmode.RequestTypeEventsAPI,
+		EnvelopeID: "envelope-1",
+		Payload:    json.RawMessage(testEventCallback),
+	})
+	defer f.Close()
+
+	orig := slackAPIURL
+	slackAPIURL = f.URL + "/api/"
+	defer func() { slackAPIURL = orig }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := Stream(ctx, slack.New("xoxb-test"), Options{Name: "team1", Mode: ModeSocket, AppToken: "xapp-test"})
+	if err != nil {
+		t.Fatalf("failed to stream: %+v", err)
+	}
+
+	var got *slack.MessageEvent
+	for got == nil {
+		select {
+		case msg, ok := <-out:
+			if !ok {
+				t.Fatalf("stream is closed before receiving message")
+			}
+			if ev, ok := msg.Data.(*slack.MessageEvent); ok {
+				got = ev
+			}
+		case <-ctx.Done():
+			t.Fatalf("timeout of receiving message")
+		}
+	}
+	if got.Channel != "C01" || got.User != "U01" || got.Text != "hello aguri" || got.Timestamp != "1600000000.000100" {
+		t.Errorf("unexpected message event: %+v", got.Msg)
+	}
+
+	select {
+	case res := <-f.acks:
+		if res.EnvelopeID != "envelope-1" {
+			t.Errorf("unexpected envelope id of ack: %s", res.EnvelopeID)
+		}
+	case <-ctx.Done():
+		t.Fatalf("timeout of receiving ack")
+	}
+
+	cancel()
+	for range out {
+		// wait closing stream
+	}
+}
+
+func TestStreamSocketModeClosedOnRunFailure(t *testing.T) {
+	f := newFakeSocketModeServer(t, "invalid_auth")
+	defer f.Close()
+
+	orig := slackAPIURL
+	slackAPIURL = f.URL + "/api/"
+	defer func() { slackAPIURL = orig }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := Stream(ctx, slack.New("xoxb-test"), Options{Name: "team1", Mode: ModeSocket, AppToken: "xapp-test"})
+	if err != nil {
+		t.Fatalf("failed to stream: %+v", err)
+	}
+
+	var connErr error
+	for {
+		select {
+		case msg, ok := <-out:
+			if !ok {
+				if connErr == nil {
+					t.Errorf("stream is closed without connection error")
+				}
+				return
+			}
+			if ev, ok := msg.Data.(*slack.ConnectionErrorEvent); ok {
+				connErr = ev.ErrorObj
+			}
+		case <-ctx.Done():
+			t.Fatalf("stream is not closed after failure of connecting")
+		}
+	}
+}
+
+func TestStreamSocketModeRequireAppToken(t *testing.T) {
+	if _, err := Stream(context.Background(), slack.New("xoxb-test"), Options{Name: "team1", Mode: ModeSocket}); err == nil {
+		t.Errorf("must be error if app_token is empty")
+	}
+}
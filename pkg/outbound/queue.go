@@ -0,0 +1,222 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// DefaultInterval is default min interval of posting per channel (chat.postMessage allow 1 message per second per channel)
+	DefaultInterval = 1 * time.Second
+	// DefaultMaxRetries is default max number of retries
+	DefaultMaxRetries = 5
+
+	idleTimeout = 5 * time.Minute
+	queueSize   = 1000
+)
+
+var (
+	// backoff of retrying errors except rate limit, variable for testing
+	minBackoff = 1 * time.Second
+	maxBackoff = 1 * time.Minute
+
+	// Default is outbound queue for aggregated slack
+	Default = NewQueue(DefaultInterval, DefaultMaxRetries)
+
+	// metrics of queue, published in /debug/vars
+	depthVar   = expvar.NewMap("aguri_outbound_queue_depth")
+	retriesVar = expvar.NewMap("aguri_outbound_retries")
+)
+
+// Queue is outbound queue per destination channel.
+// Jobs in same channel are executed in order with interval, and retried if rate limited.
+type Queue struct {
+	mu      sync.Mutex
+	workers map[string]*worker // key: channel
+
+	policyMu   sync.RWMutex
+	interval   time.Duration
+	maxRetries int
+}
+
+type job struct {
+	ctx  context.Context
+	fn   func(ctx context.Context) error
+	done chan error
+}
+
+type worker struct {
+	jobs    chan *job
+	pending int // number of jobs that are enqueuing or waiting, guarded by Queue.mu
+}
+
+// NewQueue create Queue
+func NewQueue(interval time.Duration, maxRetries int) *Queue {
+	return &Queue{
+		workers:    map[string]*worker{},
+		interval:   interval,
+		maxRetries: maxRetries,
+	}
+}
+
+// SetPolicy set interval and max retries
+func (q *Queue) SetPolicy(interval time.Duration, maxRetries int) {
+	q.policyMu.Lock()
+	defer q.policyMu.Unlock()
+
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	q.interval = interval
+	q.maxRetries = maxRetries
+}
+
+func (q *Queue) policy() (time.Duration, int) {
+	q.policyMu.RLock()
+	defer q.policyMu.RUnlock()
+	return q.interval, q.maxRetries
+}
+
+// Do enqueue fn to queue of channel, and wait the result.
+func (q *Queue) Do(ctx context.Context, channel string, fn func(ctx context.Context) error) error {
+	j := &job{ctx: ctx, fn: fn, done: make(chan error, 1)}
+
+	depthVar.Add(channel, 1)
+	defer depthVar.Add(channel, -1)
+
+	q.mu.Lock()
+	w, ok := q.workers[channel]
+	if !ok {
+		w = &worker{jobs: make(chan *job, queueSize)}
+		q.workers[channel] = w
+		go q.run(channel, w)
+	}
+	// reserve in lock, so that worker is not exited while enqueuing
+	w.pending++
+	q.mu.Unlock()
+
+	// enqueue out of lock, full queue of channel must not block other channels
+	select {
+	case w.jobs <- j:
+	case <-ctx.Done():
+		q.mu.Lock()
+		w.pending--
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Depth return number of waiting jobs of channel
+func (q *Queue) Depth(channel string) int {
+	v, ok := depthVar.Get(channel).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return int(v.Value())
+}
+
+func (q *Queue) run(channel string, w *worker) {
+	var last time.Time
+
+	for {
+		select {
+		case j := <-w.jobs:
+			q.mu.Lock()
+			w.pending--
+			q.mu.Unlock()
+
+			interval, maxRetries := q.policy()
+			if wait := interval - time.Since(last); wait > 0 {
+				time.Sleep(wait)
+			}
+			j.done <- q.execute(channel, j, maxRetries)
+			last = time.Now()
+
+		case <-time.After(idleTimeout):
+			q.mu.Lock()
+			if w.pending != 0 {
+				q.mu.Unlock()
+				continue
+			}
+			delete(q.workers, channel)
+			q.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (q *Queue) execute(channel string, j *job, maxRetries int) error {
+	backoff := minBackoff
+
+	for i := 0; ; i++ {
+		err := j.fn(j.ctx)
+		if err == nil || i >= maxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		wait := backoff
+		var rle *slack.RateLimitedError
+		if errors.As(err, &rle) && rle.RetryAfter > 0 {
+			// honour Retry-After
+			wait = rle.RetryAfter
+		} else {
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		retriesVar.Add(channel, 1)
+
+		select {
+		case <-time.After(wait):
+		case <-j.ctx.Done():
+			return j.ctx.Err()
+		}
+	}
+}
+
+// httpStatusCode is error of HTTP status (e.g. status code error of slack-go)
+type httpStatusCode interface {
+	HTTPStatusCode() int
+}
+
+// IsRetryable check err is temporary failure that may succeed by retrying,
+// rate limit, server error of Slack, network error or timeout.
+// error responses of Slack API (e.g. "channel_not_found") are permanent.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var rle *slack.RateLimitedError
+	if errors.As(err, &rle) {
+		return true
+	}
+	var sc httpStatusCode
+	if errors.As(err, &sc) {
+		return sc.HTTPStatusCode() >= http.StatusInternalServerError || sc.HTTPStatusCode() == http.StatusTooManyRequests
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,180 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// statusCodeError is fake of status code error of slack-go, that is internal package
+type statusCodeError struct {
+	code int
+}
+
+func (e statusCodeError) Error() string       { return fmt.Sprintf("slack server error: %d", e.code) }
+func (e statusCodeError) HTTPStatusCode() int { return e.code }
+
+func setBackoff(t *testing.T, min, max time.Duration) {
+	t.Helper()
+	origMin, origMax := minBackoff, maxBackoff
+	minBackoff, maxBackoff = min, max
+	t.Cleanup(func() { minBackoff, maxBackoff = origMin, origMax })
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "rate limited", err: &slack.RateLimitedError{RetryAfter: time.Second}, want: true},
+		{name: "server error", err: statusCodeError{code: 503}, want: true},
+		{name: "too many requests", err: statusCodeError{code: 429}, want: true},
+		{name: "not found", err: statusCodeError{code: 404}, want: false},
+		{name: "network error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: true},
+		{name: "unexpected eof", err: fmt.Errorf("failed to read: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "slack error response", err: slack.SlackErrorResponse{Err: "channel_not_found"}, want: false},
+		{name: "error string", err: errors.New("invalid_blocks"), want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v): want %v, but %v", tt.err, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQueueOrderPerChannel(t *testing.T) {
+	q := NewQueue(time.Millisecond, 1)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+
+	var wg sync.WaitGroup
+	for _, ch := range []string{"C1", "C2"} {
+		wg.Add(1)
+		go func(ch string) {
+			defer wg.Done()
+			// jobs of same caller are enqueued in order
+			for i := 0; i < 10; i++ {
+				i := i
+				err := q.Do(ctx, ch, func(ctx context.Context) error {
+					mu.Lock()
+					defer mu.Unlock()
+					got[ch] = append(got[ch], i)
+					return nil
+				})
+				if err != nil {
+					t.Errorf("failed to do job: %+v", err)
+				}
+			}
+		}(ch)
+	}
+	wg.Wait()
+
+	for _, ch := range []string{"C1", "C2"} {
+		if len(got[ch]) != 10 {
+			t.Fatalf("unexpected number of jobs of %s: %v", ch, got[ch])
+		}
+		for i, v := range got[ch] {
+			if v != i {
+				t.Errorf("jobs of %s are not executed in order: %v", ch, got[ch])
+				break
+			}
+		}
+	}
+}
+
+func TestQueueRetryAfter(t *testing.T) {
+	setBackoff(t, time.Hour, time.Hour)
+	q := NewQueue(time.Millisecond, 3)
+
+	var calls []time.Time
+	err := q.Do(context.Background(), "C1", func(ctx context.Context) error {
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			return &slack.RateLimitedError{RetryAfter: 50 * time.Millisecond}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("must succeed after rate limit: %+v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("unexpected number of calls: %d", len(calls))
+	}
+	// backoff is one hour, so retried by Retry-After
+	if wait := calls[1].Sub(calls[0]); wait < 50*time.Millisecond {
+		t.Errorf("retried before Retry-After: %s", wait)
+	}
+}
+
+func TestQueueBackoff(t *testing.T) {
+	setBackoff(t, 20*time.Millisecond, 50*time.Millisecond)
+	q := NewQueue(time.Millisecond, 5)
+
+	var calls []time.Time
+	err := q.Do(context.Background(), "C1", func(ctx context.Context) error {
+		calls = append(calls, time.Now())
+		if len(calls) < 4 {
+			return statusCodeError{code: 502}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("must succeed after server errors: %+v", err)
+	}
+	if len(calls) != 4 {
+		t.Fatalf("unexpected number of calls: %d", len(calls))
+	}
+
+	// 20ms, 40ms, and 50ms (max)
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond} {
+		if wait := calls[i+1].Sub(calls[i]); wait < want {
+			t.Errorf("wait of retry %d is shorter than backoff: want %s, but %s", i+1, want, wait)
+		}
+	}
+}
+
+func TestQueueRetryLimit(t *testing.T) {
+	setBackoff(t, time.Millisecond, time.Millisecond)
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "retryable", err: statusCodeError{code: 500}, wantCalls: 3},
+		{name: "permanent", err: slack.SlackErrorResponse{Err: "is_archived"}, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue(time.Millisecond, 2)
+
+			var calls int
+			err := q.Do(context.Background(), "C1", func(ctx context.Context) error {
+				calls++
+				return tt.err
+			})
+			if err == nil || err.Error() != tt.err.Error() {
+				t.Errorf("unexpected error: %+v", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("unexpected number of calls: want %d, but %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
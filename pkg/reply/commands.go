@@ -53,6 +53,10 @@ func HandleAguriCommands(ctx context.Context, text, workspace string) error {
 		}
 		return fmt.Errorf("Usage: \\aguri create channel <channel name>")
 
+	case "filters":
+		// show effective filter of workspace
+		return commandFilters(ctx, workspace)
+
 	case "history":
 		// return message history that recent message
 		if len(texts) == 3 {
@@ -118,13 +122,28 @@ func commandList(ctx context.Context, workspace, target string) error {
 	param := slack.PostMessageParameters{
 		AsUser: true,
 	}
-	_, _, err = toAPI.PostMessageContext(ctx, config.PrefixSlackChannel+workspace, slack.MsgOptionText(msg, false), slack.MsgOptionPostMessageParameters(param))
+	channelID, err := getAggrChannelID(ctx, toAPI, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to post message: %w", err)
+	}
+	_, _, err = utils.PostMessageQueued(ctx, toAPI, channelID, slack.MsgOptionText(msg, false), slack.MsgOptionPostMessageParameters(param))
 	if err != nil {
 		return fmt.Errorf("failed to post message: %w", err)
 	}
 	return nil
 }
 
+// getAggrChannelID get id of aggregated channel of workspace.
+// outbound queue is keyed by channel id, so name must not be used for posting.
+func getAggrChannelID(ctx context.Context, toAPI *slack.Client, workspace string) (string, error) {
+	name := config.GetToChannelName(workspace)
+	isExist, ch, err := utils.IsExistChannel(ctx, toAPI, name)
+	if !isExist {
+		return "", fmt.Errorf("aggregated channel is not found: %w", err)
+	}
+	return ch.ID, nil
+}
+
 func commandPost(ctx context.Context, workspace, channel, body string) error {
 	param := slack.PostMessageParameters{
 		AsUser: true,
@@ -169,14 +188,19 @@ func commandGetHistory(ctx context.Context, workspace, channel string, limit int
 		return fmt.Errorf("failed to get history: %w", err)
 	}
 
+	aggrChannelID, err := getAggrChannelID(ctx, toAPI, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+
 	resMsg := fmt.Sprintf("%s history...\n", channel)
 	param := slack.PostMessageParameters{
 		Username:  "aguri@s:system",
 		IconEmoji: ":ghost",
 	}
 
-	_, _, err = toAPI.PostMessageContext(ctx,
-		config.PrefixSlackChannel+workspace,
+	_, _, err = utils.PostMessageQueued(ctx, toAPI,
+		aggrChannelID,
 		slack.MsgOptionText(resMsg, false),
 		slack.MsgOptionPostMessageParameters(param),
 	)
@@ -199,15 +223,15 @@ func commandGetHistory(ctx context.Context, workspace, channel string, limit int
 			param.Username = utils.GenerateAguriUsername(ch, "SLACKBOT")
 		} else {
 			// bot
-			botInfo, err := fromAPI.GetBotInfoContext(ctx, m.BotID)
+			botInfo, err := utils.GetDirectory(fromAPI).GetBotInfo(ctx, m.BotID)
 			if err != nil {
 				return fmt.Errorf("failed to get history: %w", err)
 			}
 			param.Username = utils.GenerateAguriUsername(ch, botInfo.Name)
 		}
 
-		_, _, err = toAPI.PostMessageContext(ctx,
-			config.PrefixSlackChannel+workspace,
+		_, _, err = utils.PostMessageQueued(ctx, toAPI,
+			aggrChannelID,
 			slack.MsgOptionText(m.Text, false),
 			slack.MsgOptionAttachments(m.Attachments...),
 			slack.MsgOptionPostMessageParameters(param),
@@ -219,3 +243,27 @@ func commandGetHistory(ctx context.Context, workspace, channel string, limit int
 
 	return nil
 }
+
+func commandFilters(ctx context.Context, workspace string) error {
+	msg := fmt.Sprintf("filters of %s\n%s", workspace, config.GetFilter(workspace).String())
+	param := slack.PostMessageParameters{
+		Username:  "aguri@s:system",
+		IconEmoji: ":ghost:",
+	}
+
+	toAPI := store.GetConfigToAPI()
+	channelID, err := getAggrChannelID(ctx, toAPI, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to post filters: %w", err)
+	}
+	_, _, err = utils.PostMessageQueued(ctx, toAPI,
+		channelID,
+		slack.MsgOptionText(msg, false),
+		slack.MsgOptionPostMessageParameters(param),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to post filters: %w", err)
+	}
+
+	return nil
+}
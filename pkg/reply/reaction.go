@@ -0,0 +1,77 @@
+package reply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/store"
+	"github.com/whywaita/aguri/pkg/utils"
+)
+
+// handleReaction mirror reaction in aggregated channel to source message by token of source slack
+func handleReaction(ctx context.Context, toAPI *slack.Client, r utils.Reaction) error {
+	if !config.GetTo().ReverseReactions {
+		return nil
+	}
+
+	self, err := utils.GetDirectory(toAPI).GetSelf(ctx)
+	if err != nil {
+		return err
+	}
+	if r.User == self.UserID {
+		// mirrored from source slack by aguri
+		return nil
+	}
+
+	aggrCh, ok, err := utils.ResolveAggrChannel(ctx, toAPI, r.Channel)
+	if err != nil {
+		return fmt.Errorf("failed to resolve aggregated channel: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	logData, err := utils.LookupAggregatedSlackLog(ctx, toAPI, aggrCh.Workspace, r.Channel, r.Timestamp)
+	if errors.Is(err, store.ErrSourceChannelNotFound) || errors.Is(err, store.ErrSlackLogEvicted) {
+		// not aggregated message
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stored slack log: %w", err)
+	}
+	if logData.SourceChannelID == "" || logData.SourceTimestamp == "" {
+		// stored by old version, source message is unknown
+		return nil
+	}
+
+	workspace := aggrCh.Workspace
+	if logData.Workspace != "" {
+		workspace = logData.Workspace
+	}
+	fromAPI := store.GetSlackAPIInstance(workspace)
+
+	if r.Added {
+		utils.MarkMirroredReaction(workspace, logData.SourceChannelID, logData.SourceTimestamp, r.Name, true)
+		err := utils.AddReactionQueued(ctx, fromAPI, logData.SourceChannelID, logData.SourceTimestamp, r.Name)
+		if err != nil && !errors.Is(err, utils.ErrEmojiNotFound) {
+			return fmt.Errorf("failed to add reaction: %w", err)
+		}
+		return nil
+	}
+
+	// reaction of source slack is shared by all users of aggregated slack
+	still, err := utils.HasReactionByOthers(ctx, toAPI, r.Channel, r.Timestamp, r.Name, self.UserID)
+	if err != nil {
+		return err
+	}
+	if still {
+		return nil
+	}
+	utils.MarkMirroredReaction(workspace, logData.SourceChannelID, logData.SourceTimestamp, r.Name, false)
+	if err := utils.RemoveReactionQueued(ctx, fromAPI, logData.SourceChannelID, logData.SourceTimestamp, r.Name); err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	return nil
+}
@@ -4,26 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"regexp"
 	"strings"
 
 	"github.com/slack-go/slack"
-	"github.com/slack-go/slack/slackutilsx"
 	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/ingest"
 	"github.com/whywaita/aguri/pkg/store"
 	"github.com/whywaita/aguri/pkg/utils"
 )
 
-var (
-	reChannel = regexp.MustCompile(`(\S+)@(\S+):(\S+)`)
-)
-
-func validateMessage(fromType, aggrChannelName string, ev *slack.MessageEvent) bool {
-	if !strings.Contains(aggrChannelName, config.PrefixSlackChannel) {
-		// not aggr channel
-		return false
-	}
-
+func validateMessage(ev *slack.MessageEvent) bool {
 	if ev.Msg.User == "USLACKBOT" {
 		return false
 	}
@@ -33,32 +23,41 @@ func validateMessage(fromType, aggrChannelName string, ev *slack.MessageEvent) b
 		return false
 	}
 
-	if fromType != slackutilsx.CTypeChannel.String() && fromType != slackutilsx.CTypeGroup.String() {
-		// TODO: implement other type
-		return false
-	}
-
 	return true
 }
 
-func validateParsedMessage(userNames [][]string) bool {
-	if len(userNames) == 0 {
+// isPostedByAguri check message is aggregated message that posted by aguri
+func isPostedByAguri(ev *slack.MessageEvent) bool {
+	if ev.SubType == "bot_message" {
+		return true
+	}
+	if ev.BotID == "" {
 		return false
 	}
-
-	return true
+	_, _, _, ok := utils.ParseAguriUsername(ev.Username)
+	return ok
 }
 
 // HandleReplyMessage handle reply message from aggregated channel
 func HandleReplyMessage(ctx context.Context, loggerMap *store.SyncLoggerMap) error {
 	toAPI := store.GetConfigToAPI()
-	rtm := toAPI.NewRTM(slack.RTMOptionUseStart(false))
-	go rtm.ManageConnection()
+	if err := utils.RefreshAggrChannels(ctx, toAPI); err != nil {
+		return fmt.Errorf("failed to refresh aggregated channels: %w", err)
+	}
+
+	events, err := ingest.Stream(ctx, toAPI, config.GetTo().IngestOptions())
+	if err != nil {
+		return fmt.Errorf("failed to start receiving events: %w", err)
+	}
 
 	for {
 		select {
-		case msg := <-rtm.IncomingEvents:
-			if err := handleIncomingEvents(ctx, msg, toAPI, loggerMap); err != nil {
+		case msg, ok := <-events:
+			if !ok || ctx.Err() != nil {
+				return nil
+			}
+			// complete posting even if shutting down
+			if err := handleIncomingEvents(utils.WithoutCancel(ctx), msg, toAPI, loggerMap); err != nil {
 				log.Println(err)
 			}
 
@@ -69,30 +68,58 @@ func HandleReplyMessage(ctx context.Context, loggerMap *store.SyncLoggerMap) err
 }
 
 func handleIncomingEvents(ctx context.Context, msg slack.RTMEvent, toAPI *slack.Client, loggerMap *store.SyncLoggerMap) error {
+	if utils.HandleDirectoryEvent(toAPI, msg) {
+		switch msg.Data.(type) {
+		case *slack.ChannelCreatedEvent, *slack.GroupCreatedEvent, *slack.ChannelRenameEvent, *slack.GroupRenameEvent:
+			// aggregated channel may be created or renamed
+			return utils.RefreshAggrChannels(ctx, toAPI)
+		}
+		return nil
+	}
+
 	switch ev := msg.Data.(type) {
 	case *slack.MessageEvent:
-		fromType, aggrChName, err := utils.ConvertDisplayChannelName(ctx, toAPI, ev)
+		aggrCh, ok, err := utils.ResolveAggrChannel(ctx, toAPI, ev.Channel)
 		if err != nil {
-			return fmt.Errorf("failed to convert display channel name: %w", err)
-
+			return fmt.Errorf("failed to resolve aggregated channel: %w", err)
+		}
+		if !ok {
+			// not aggr channel
+			return nil
 		}
-		if !validateMessage(fromType, aggrChName, ev) {
+		if !validateMessage(ev) {
 			// invalid message
 			return nil
 		}
 
-		workspace := strings.TrimPrefix(aggrChName, config.PrefixSlackChannel)
+		workspace := aggrCh.Workspace
 		if ev.ThreadTimestamp == "" {
 			// maybe not in thread
+			if workspace == "" {
+				// destination of route, source workspace is resolved by thread
+				return nil
+			}
 			if err := handleReplyNotInThreadMessage(ctx, ev, workspace, loggerMap); err != nil {
 				return fmt.Errorf("failed to handle receive message: %w", err)
 			}
+			return nil
 		}
 
+		if isPostedByAguri(ev) {
+			// message of source thread that is forwarded by aguri, avoid loop
+			return nil
+		}
 		if err := handleReplyInThreadMessage(ctx, ev, workspace); err != nil {
 			return fmt.Errorf("failed to handle reply message: %w", err)
 		}
 
+	case *slack.ReactionAddedEvent, *slack.ReactionRemovedEvent:
+		if r, ok := utils.ParseReactionEvent(ev); ok {
+			if err := handleReaction(ctx, toAPI, r); err != nil {
+				return fmt.Errorf("failed to handle reaction: %w", err)
+			}
+		}
+
 	case *slack.RTMError:
 		return fmt.Errorf("detect rtm error: %s", ev.Error())
 	}
@@ -102,22 +129,41 @@ func handleIncomingEvents(ctx context.Context, msg slack.RTMEvent, toAPI *slack.
 
 func handleReplyInThreadMessage(ctx context.Context, ev *slack.MessageEvent, workspace string) error {
 	// reply message toSlack to fromSlack
-	logData, err := store.GetSlackLog(workspace, ev.ThreadTimestamp)
+	logData, err := utils.LookupAggregatedSlackLog(ctx, store.GetConfigToAPI(), workspace, ev.Channel, ev.ThreadTimestamp)
 	if err != nil {
 		return fmt.Errorf("failed to get stored slack log: %w", err)
 	}
 
+	if logData.Workspace != "" {
+		// aggregated channel may be shared by some workspaces
+		workspace = logData.Workspace
+	}
+
 	// Post
 	api := store.GetSlackAPIInstance(workspace)
 	param := slack.PostMessageParameters{
 		AsUser: true,
 	}
-
-	_, _, err = api.PostMessageContext(ctx,
-		logData.Channel,
-		slack.MsgOptionText(ev.Text, false),
+	// mentions of aggregated slack are not valid in source slack
+	text := utils.RewriteMentionsToSource(ctx, store.GetConfigToAPI(), api, workspace, ev.Text)
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
 		slack.MsgOptionPostMessageParameters(param),
-	)
+	}
+
+	channel := logData.Channel
+	if logData.SourceChannelID != "" {
+		channel = logData.SourceChannelID
+	}
+	if threadTimestamp := logData.SourceThread(); threadTimestamp != "" {
+		// reply to original thread
+		opts = append(opts, slack.MsgOptionTS(threadTimestamp))
+		if config.GetTo().ReplyBroadcast {
+			opts = append(opts, slack.MsgOptionBroadcast())
+		}
+	}
+
+	_, _, err = api.PostMessageContext(ctx, channel, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to post message: %w", err)
 	}
@@ -140,37 +186,7 @@ func handleReplyNotInThreadMessage(ctx context.Context, ev *slack.MessageEvent,
 				return nil
 			}
 		}
-	} else {
-		// write on fromSlack
-		err := saveSlackLogs(ev, workspace)
-		if err != nil {
-			logger.Warn(err)
-			return nil
-		}
-	}
-
-	return nil
-}
-
-func saveSlackLogs(ev *slack.MessageEvent, workspace string) error {
-	// save slack log to store package
-	if ev.Username == "" {
-		return fmt.Errorf("Username is not found")
-	}
-
-	// parse username
-	userNames := reChannel.FindAllStringSubmatch(ev.Username, -1)
-	if !validateParsedMessage(userNames) {
-		// miss regexp
-		// or not channel
-		return fmt.Errorf("failed to validate message")
-	}
-
-	if len(userNames[0]) < 3 {
-		return fmt.Errorf("failed to get source channel name: %s", userNames[0])
 	}
-	chName := userNames[0][3]
-	store.SetSlackLog(workspace, ev.Timestamp, chName, ev.Text, "", "")
 
 	return nil
 }
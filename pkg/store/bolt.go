@@ -0,0 +1,488 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketSlackLog = []byte("slack_log")
+	bucketEvicted  = []byte("slack_log_evicted") // key: workspace, value: newest evicted timestamp
+	bucketReverse  = []byte("reverse_log")       // key: aggregated channel id and timestamp, value: LogData
+	bucketOutbox   = []byte("outbox")            // key: workspace and timestamp, value: OutboxItem
+	bucketMarker   = []byte("outbox_delivered")  // key: workspace and timestamp, value: time of delivered
+	bucketCursor   = []byte("cursor")            // key: workspace and channel id, value: timestamp
+	bucketFile     = []byte("file")              // key: workspace, file id and aggregated channel id, value: FileMapping
+)
+
+// BoltLogStore is LogStore in BoltDB file
+type BoltLogStore struct {
+	db *bolt.DB
+}
+
+// NewBoltLogStore open BoltDB file and create BoltLogStore
+func NewBoltLogStore(path string) (*BoltLogStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path of bolt store is required")
+	}
+
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt db (path: %s): %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketSlackLog, bucketEvicted, bucketReverse, bucketOutbox, bucketMarker, bucketCursor, bucketFile} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	return &BoltLogStore{db: db}, nil
+}
+
+// Set register LogData
+func (b *BoltLogStore) Set(workspace, timestamp string, data LogData) error {
+	v, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log data: %w", err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSlackLog).Put([]byte(logKey(workspace, timestamp)), v)
+	})
+}
+
+// Get retrieve LogData
+func (b *BoltLogStore) Get(workspace, timestamp string) (*LogData, error) {
+	var d LogData
+	var found bool
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketSlackLog).Get([]byte(logKey(workspace, timestamp)))
+		if v == nil {
+			if w := tx.Bucket(bucketEvicted).Get([]byte(workspace)); w != nil && !tsLess(string(w), timestamp) {
+				return ErrSlackLogEvicted
+			}
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &d)
+	})
+	if err == ErrSlackLogEvicted {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log data: %w", err)
+	}
+	if !found {
+		return nil, ErrSourceChannelNotFound
+	}
+
+	return &d, nil
+}
+
+// SetReverse register LogData keyed by aggregated message
+func (b *BoltLogStore) SetReverse(aggrChannelID, timestamp string, data LogData) error {
+	v, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log data: %w", err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketReverse).Put([]byte(logKey(aggrChannelID, timestamp)), v)
+	})
+}
+
+// GetReverse retrieve LogData keyed by aggregated message
+func (b *BoltLogStore) GetReverse(aggrChannelID, timestamp string) (*LogData, error) {
+	var d LogData
+	var found bool
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketReverse).Get([]byte(logKey(aggrChannelID, timestamp)))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &d)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log data: %w", err)
+	}
+	if !found {
+		return nil, ErrSourceChannelNotFound
+	}
+
+	return &d, nil
+}
+
+// SweepReverse evict LogData keyed by aggregated message
+func (b *BoltLogStore) SweepReverse(r Retention, now time.Time) (int, error) {
+	var evicted int
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		isSourceEvicted := func(workspace, timestamp string) bool {
+			if tx.Bucket(bucketSlackLog).Get([]byte(logKey(workspace, timestamp))) != nil {
+				return false
+			}
+			w := tx.Bucket(bucketEvicted).Get([]byte(workspace))
+			return w != nil && !tsLess(string(w), timestamp)
+		}
+
+		var targets [][]byte
+		if err := tx.Bucket(bucketReverse).ForEach(func(k, v []byte) error {
+			i := strings.LastIndex(string(k), ",")
+			if i < 0 {
+				return nil
+			}
+			var d LogData
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			if isReverseEvicted(string(k[i+1:]), d, r, now, isSourceEvicted) {
+				// copy key, it is valid only in transaction and can't be deleted in ForEach
+				targets = append(targets, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range targets {
+			if err := tx.Bucket(bucketReverse).Delete(k); err != nil {
+				return err
+			}
+		}
+		evicted = len(targets)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to sweep reverse log data: %w", err)
+	}
+
+	return evicted, nil
+}
+
+// Sweep evict LogData by retention policy
+func (b *BoltLogStore) Sweep(r Retention, now time.Time) (map[string]int, error) {
+	evicted := map[string]int{}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		logs := map[string][]string{} // key: workspace, value: timestamps
+		if err := tx.Bucket(bucketSlackLog).ForEach(func(k, _ []byte) error {
+			i := strings.LastIndex(string(k), ",")
+			if i < 0 {
+				return nil
+			}
+			workspace, ts := string(k[:i]), string(k[i+1:])
+			logs[workspace] = append(logs[workspace], ts)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for workspace, timestamps := range logs {
+			sortTimestamps(timestamps)
+			targets := selectEvictTimestamps(timestamps, r, now)
+			if len(targets) == 0 {
+				continue
+			}
+			for _, ts := range targets {
+				if err := tx.Bucket(bucketSlackLog).Delete([]byte(logKey(workspace, ts))); err != nil {
+					return err
+				}
+			}
+			if err := tx.Bucket(bucketEvicted).Put([]byte(workspace), []byte(targets[len(targets)-1])); err != nil {
+				return err
+			}
+			evicted[workspace] = len(targets)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sweep log data: %w", err)
+	}
+
+	return evicted, nil
+}
+
+// SweepOutbox evict old undelivered events and markers of delivered events
+func (b *BoltLogStore) SweepOutbox(r Retention, now time.Time) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		items := map[string][]OutboxItem{} // key: workspace
+		if err := tx.Bucket(bucketOutbox).ForEach(func(_, v []byte) error {
+			var item OutboxItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+			items[item.Workspace] = append(items[item.Workspace], item)
+			return nil
+		}); err != nil {
+			return err
+		}
+		for workspace, list := range items {
+			for _, ts := range selectEvictOutbox(list, r, now) {
+				if err := tx.Bucket(bucketOutbox).Delete([]byte(logKey(workspace, ts))); err != nil {
+					return err
+				}
+			}
+		}
+
+		var expired [][]byte
+		if err := tx.Bucket(bucketMarker).ForEach(func(k, v []byte) error {
+			var at time.Time
+			if err := at.UnmarshalText(v); err != nil || isMarkerExpired(at, now) {
+				// copy key, it is valid only in transaction and can't be deleted in ForEach
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := tx.Bucket(bucketMarker).Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sweep outbox: %w", err)
+	}
+
+	return nil
+}
+
+// AddOutbox record event
+func (b *BoltLogStore) AddOutbox(item OutboxItem) (bool, error) {
+	v, err := json.Marshal(item)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal outbox item: %w", err)
+	}
+
+	var added bool
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(logKey(item.Workspace, item.Timestamp))
+		if tx.Bucket(bucketOutbox).Get(key) != nil || tx.Bucket(bucketMarker).Get(key) != nil {
+			return nil
+		}
+		added = true
+		return tx.Bucket(bucketOutbox).Put(key, v)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to add outbox item: %w", err)
+	}
+
+	return added, nil
+}
+
+// DoneOutbox remove delivered event and keep marker
+func (b *BoltLogStore) DoneOutbox(workspace, timestamp string, now time.Time) error {
+	at, err := now.MarshalText()
+	if err != nil {
+		return fmt.Errorf("failed to marshal time: %w", err)
+	}
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(logKey(workspace, timestamp))
+		if err := tx.Bucket(bucketOutbox).Delete(key); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketMarker).Put(key, at)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to done outbox item: %w", err)
+	}
+
+	return nil
+}
+
+// PendingOutbox get undelivered events
+func (b *BoltLogStore) PendingOutbox(workspace string) ([]OutboxItem, error) {
+	var items []OutboxItem
+	prefix := []byte(logKey(workspace, ""))
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketOutbox).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var item OutboxItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+			if item.Workspace != workspace {
+				continue
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending outbox items: %w", err)
+	}
+	sortOutboxItems(items)
+
+	return items, nil
+}
+
+// AdvanceCursor set timestamp of channel if it is newer
+func (b *BoltLogStore) AdvanceCursor(workspace, channelID, timestamp string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(logKey(workspace, channelID))
+		if cur := tx.Bucket(bucketCursor).Get(key); cur != nil && !tsLess(string(cur), timestamp) {
+			return nil
+		}
+		return tx.Bucket(bucketCursor).Put(key, []byte(timestamp))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put cursor: %w", err)
+	}
+	return nil
+}
+
+// GetCursors get timestamps of channels
+func (b *BoltLogStore) GetCursors(workspace string) (map[string]string, error) {
+	cursors := map[string]string{}
+	prefix := []byte(logKey(workspace, ""))
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketCursor).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			channelID := string(k[len(prefix):])
+			if strings.Contains(channelID, ",") {
+				// other workspace that has same prefix
+				continue
+			}
+			cursors[channelID] = string(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cursors: %w", err)
+	}
+	return cursors, nil
+}
+
+// SetFileMapping register FileMapping
+func (b *BoltLogStore) SetFileMapping(workspace, fileID string, m FileMapping) error {
+	v, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal file mapping: %w", err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketFile).Put([]byte(fileKey(workspace, fileID, m.ChannelID)), v)
+	})
+}
+
+// GetFileMapping retrieve FileMapping
+func (b *BoltLogStore) GetFileMapping(workspace, fileID, aggrChannelID string) (*FileMapping, error) {
+	var m FileMapping
+	var found bool
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketFile).Get([]byte(fileKey(workspace, fileID, aggrChannelID)))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &m)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file mapping: %w", err)
+	}
+	if !found {
+		return nil, ErrFileMappingNotFound
+	}
+
+	return &m, nil
+}
+
+// ListFileMappings retrieve FileMapping of all aggregated channels
+func (b *BoltLogStore) ListFileMappings(workspace, fileID string) ([]FileMapping, error) {
+	var mappings []FileMapping
+	prefix := []byte(fileKey(workspace, fileID, ""))
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketFile).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var m FileMapping
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			}
+			mappings = append(mappings, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file mappings: %w", err)
+	}
+
+	return mappings, nil
+}
+
+// DeleteFileMapping delete FileMapping
+func (b *BoltLogStore) DeleteFileMapping(workspace, fileID, aggrChannelID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketFile).Delete([]byte(fileKey(workspace, fileID, aggrChannelID)))
+	})
+}
+
+// SweepFileMapping evict FileMapping
+func (b *BoltLogStore) SweepFileMapping(r Retention, now time.Time) (int, error) {
+	var evicted int
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		isSourceEvicted := func(workspace, timestamp string) bool {
+			if tx.Bucket(bucketSlackLog).Get([]byte(logKey(workspace, timestamp))) != nil {
+				return false
+			}
+			w := tx.Bucket(bucketEvicted).Get([]byte(workspace))
+			return w != nil && !tsLess(string(w), timestamp)
+		}
+
+		var targets [][]byte
+		if err := tx.Bucket(bucketFile).ForEach(func(k, v []byte) error {
+			var m FileMapping
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			}
+			workspace := strings.SplitN(string(k), ",", 2)[0]
+			if isFileMappingEvicted(workspace, m, r, now, isSourceEvicted) {
+				// copy key, it is valid only in transaction and can't be deleted in ForEach
+				targets = append(targets, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range targets {
+			if err := tx.Bucket(bucketFile).Delete(k); err != nil {
+				return err
+			}
+		}
+		evicted = len(targets)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to sweep file mapping: %w", err)
+	}
+
+	return evicted, nil
+}
+
+// Close close BoltDB file
+func (b *BoltLogStore) Close() error {
+	return b.db.Close()
+}
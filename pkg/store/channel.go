@@ -0,0 +1,35 @@
+package store
+
+import "sync"
+
+// AggrChannel is channel of aggregated slack
+type AggrChannel struct {
+	ID        string
+	Name      string
+	Workspace string // name of source workspace, empty if channel is shared by routes
+}
+
+var (
+	aggrChannels   = map[string]AggrChannel{} // key: channel id
+	aggrChannelsMu sync.RWMutex
+)
+
+// SetAggrChannels set mapping of aggregated channels
+func SetAggrChannels(channels []AggrChannel) {
+	m := map[string]AggrChannel{}
+	for _, c := range channels {
+		m[c.ID] = c
+	}
+
+	aggrChannelsMu.Lock()
+	defer aggrChannelsMu.Unlock()
+	aggrChannels = m
+}
+
+// GetAggrChannel get aggregated channel by channel id
+func GetAggrChannel(channelID string) (AggrChannel, bool) {
+	aggrChannelsMu.RLock()
+	defer aggrChannelsMu.RUnlock()
+	c, ok := aggrChannels[channelID]
+	return c, ok
+}
@@ -1,6 +1,8 @@
 package store
 
 import (
+	"sync"
+
 	"github.com/slack-go/slack"
 )
 
@@ -9,52 +11,93 @@ var (
 	fromAPITokens map[string]string
 	toAPI         *slack.Client
 	toAPIToken    string
+
+	configMu sync.RWMutex
 )
 
 // SetConfigFromTokens set token
 func SetConfigFromTokens(inputs map[string]string) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	fromAPITokens = inputs
 }
 
 // SetConfigToAPIToken set token and create API
 func SetConfigToAPIToken(token string) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	toAPIToken = token
 	toAPI = slack.New(token)
 }
 
+// SetConfigToAPI set api instance (e.g. created with options)
+func SetConfigToAPI(api *slack.Client) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	toAPI = api
+}
+
 // GetConfigFromAPITokens get tokens
 func GetConfigFromAPITokens() map[string]string {
+	configMu.RLock()
+	defer configMu.RUnlock()
 	return fromAPITokens
 }
 
 // GetConfigFromAPI get token
 func GetConfigFromAPI(workspaceName string) (token string) {
+	configMu.RLock()
+	defer configMu.RUnlock()
 	return fromAPITokens[workspaceName]
 }
 
 // GetConfigToAPIToken get token
 func GetConfigToAPIToken() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
 	return toAPIToken
 }
 
 // GetConfigToAPI get api instance
 func GetConfigToAPI() *slack.Client {
+	configMu.RLock()
+	defer configMu.RUnlock()
 	return toAPI
 }
 
 // SetFromApis set api instances
 func SetFromApis(inputs map[string]*slack.Client) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	fromApis = inputs
 }
 
 // GetSlackAPIInstance get api instance
 func GetSlackAPIInstance(workspaceName string) *slack.Client {
+	configMu.Lock()
+	defer configMu.Unlock()
+
 	api, ok := fromApis[workspaceName]
 	if ok == false {
 		// not found
-		api = slack.New(GetConfigFromAPI(workspaceName))
+		api = slack.New(fromAPITokens[workspaceName])
 		fromApis[workspaceName] = api
 	}
 
 	return api
 }
+
+// GetSlackAPIInstances get api instances of current config, including aggregated slack
+func GetSlackAPIInstances() []*slack.Client {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	apis := make([]*slack.Client, 0, len(fromApis)+1)
+	for _, api := range fromApis {
+		apis = append(apis, api)
+	}
+	if toAPI != nil {
+		apis = append(apis, toAPI)
+	}
+	return apis
+}
@@ -0,0 +1,31 @@
+package store
+
+import "fmt"
+
+// CursorStore is storage of timestamp of last processed message per source channel
+type CursorStore interface {
+	// AdvanceCursor set timestamp of channel if it is newer than current one
+	AdvanceCursor(workspace, channelID, timestamp string) error
+	// GetCursors get timestamps of all channels in workspace (key: channel id)
+	GetCursors(workspace string) (map[string]string, error)
+}
+
+// AdvanceCursor set timestamp of last processed message in channel
+func AdvanceCursor(workspace, channelID, timestamp string) error {
+	if channelID == "" || timestamp == "" {
+		return nil
+	}
+	if err := GetLogStore().AdvanceCursor(workspace, channelID, timestamp); err != nil {
+		return fmt.Errorf("failed to advance cursor: %w", err)
+	}
+	return nil
+}
+
+// GetCursors get timestamps of last processed message per channel
+func GetCursors(workspace string) (map[string]string, error) {
+	cursors, err := GetLogStore().GetCursors(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cursors: %w", err)
+	}
+	return cursors, nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FileStore is storage of mapping of source file and mirrored file
+type FileStore interface {
+	// SetFileMapping register FileMapping to key of workspace, source file id and aggregated channel id
+	SetFileMapping(workspace, fileID string, m FileMapping) error
+	// GetFileMapping retrieve FileMapping by workspace, source file id and aggregated channel id
+	GetFileMapping(workspace, fileID, aggrChannelID string) (*FileMapping, error)
+	// ListFileMappings retrieve FileMapping of all aggregated channels by workspace and source file id
+	ListFileMappings(workspace, fileID string) ([]FileMapping, error)
+	// DeleteFileMapping delete FileMapping
+	DeleteFileMapping(workspace, fileID, aggrChannelID string) error
+	// SweepFileMapping evict FileMapping that is older than max age or that source LogData is already evicted,
+	// and return number of evicted FileMapping. It must be called after Sweep.
+	SweepFileMapping(r Retention, now time.Time) (int, error)
+}
+
+// FileMapping is mirrored file of source file in aggregated slack
+type FileMapping struct {
+	FileID          string // id of uploaded file, empty if only permalink is posted
+	ChannelID       string // id of aggregated channel
+	Timestamp       string // timestamp of message that share uploaded file or post permalink
+	SourceTimestamp string // timestamp of source message that share file at first
+}
+
+var (
+	// ErrFileMappingNotFound is error message for file is not mirrored
+	ErrFileMappingNotFound = fmt.Errorf("file mapping is not found")
+)
+
+func fileKey(workspace, fileID, aggrChannelID string) string {
+	return strings.Join([]string{workspace, fileID, aggrChannelID}, ",")
+}
+
+// SetFileMapping set mapping of mirrored file
+func SetFileMapping(workspace, fileID string, m FileMapping) error {
+	if err := GetLogStore().SetFileMapping(workspace, fileID, m); err != nil {
+		return fmt.Errorf("failed to set file mapping: %w", err)
+	}
+	return nil
+}
+
+// GetFileMapping get mapping of mirrored file in aggregated channel
+func GetFileMapping(workspace, fileID, aggrChannelID string) (*FileMapping, error) {
+	return GetLogStore().GetFileMapping(workspace, fileID, aggrChannelID)
+}
+
+// ListFileMappings get mappings of mirrored file in all aggregated channels
+func ListFileMappings(workspace, fileID string) ([]FileMapping, error) {
+	mappings, err := GetLogStore().ListFileMappings(workspace, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// DeleteFileMapping delete mapping of mirrored file
+func DeleteFileMapping(workspace, fileID, aggrChannelID string) error {
+	if err := GetLogStore().DeleteFileMapping(workspace, fileID, aggrChannelID); err != nil {
+		return fmt.Errorf("failed to delete file mapping: %w", err)
+	}
+	return nil
+}
+
+// isFileMappingEvicted check FileMapping must be evicted.
+// isSourceEvicted report source LogData of workspace and timestamp is already evicted.
+func isFileMappingEvicted(workspace string, m FileMapping, r Retention, now time.Time, isSourceEvicted func(workspace, timestamp string) bool) bool {
+	if m.SourceTimestamp == "" {
+		// stored by old version, that can't be found by aggregated channel
+		return true
+	}
+	if r.MaxAge > 0 && tsToTime(m.SourceTimestamp).Before(now.Add(-r.MaxAge)) {
+		return true
+	}
+	return isSourceEvicted(workspace, m.SourceTimestamp)
+}
@@ -31,3 +31,8 @@ func (s *SyncLoggerMap) Load(workspace string) (*logrus.Logger, error) {
 
 	return v.(*logrus.Logger), nil
 }
+
+// Delete delete logger
+func (s *SyncLoggerMap) Delete(workspace string) {
+	s.s.Delete(workspace)
+}
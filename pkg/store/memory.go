@@ -0,0 +1,324 @@
+package store
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryLogStore is LogStore in memory
+type MemoryLogStore struct {
+	mu      sync.RWMutex
+	log     map[string]map[string]LogData     // key: workspace, timestamp
+	reverse map[string]map[string]LogData     // key: aggregated channel id, timestamp
+	evicted map[string]string                 // key: workspace, value: newest evicted timestamp
+	outbox  map[string]map[string]OutboxItem  // key: workspace, timestamp
+	markers map[string]map[string]time.Time   // key: workspace, timestamp of delivered event
+	cursors map[string]map[string]string      // key: workspace, channel id
+	files   map[string]map[string]FileMapping // key: workspace and file id, aggregated channel id
+}
+
+// NewMemoryLogStore create MemoryLogStore
+func NewMemoryLogStore() *MemoryLogStore {
+	return &MemoryLogStore{
+		log:     map[string]map[string]LogData{},
+		reverse: map[string]map[string]LogData{},
+		evicted: map[string]string{},
+		outbox:  map[string]map[string]OutboxItem{},
+		markers: map[string]map[string]time.Time{},
+		cursors: map[string]map[string]string{},
+		files:   map[string]map[string]FileMapping{},
+	}
+}
+
+// Set register LogData
+func (m *MemoryLogStore) Set(workspace, timestamp string, data LogData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.log[workspace]; !ok {
+		m.log[workspace] = map[string]LogData{}
+	}
+	m.log[workspace][timestamp] = data
+	return nil
+}
+
+// Get retrieve LogData
+func (m *MemoryLogStore) Get(workspace, timestamp string) (*LogData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	val, ok := m.log[workspace][timestamp]
+	if !ok {
+		if w, ok := m.evicted[workspace]; ok && !tsLess(w, timestamp) {
+			return nil, ErrSlackLogEvicted
+		}
+		return nil, ErrSourceChannelNotFound
+	}
+
+	return &val, nil
+}
+
+// SetReverse register LogData keyed by aggregated message
+func (m *MemoryLogStore) SetReverse(aggrChannelID, timestamp string, data LogData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.reverse[aggrChannelID]; !ok {
+		m.reverse[aggrChannelID] = map[string]LogData{}
+	}
+	m.reverse[aggrChannelID][timestamp] = data
+	return nil
+}
+
+// GetReverse retrieve LogData keyed by aggregated message
+func (m *MemoryLogStore) GetReverse(aggrChannelID, timestamp string) (*LogData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	val, ok := m.reverse[aggrChannelID][timestamp]
+	if !ok {
+		return nil, ErrSourceChannelNotFound
+	}
+	return &val, nil
+}
+
+// SweepReverse evict LogData keyed by aggregated message
+func (m *MemoryLogStore) SweepReverse(r Retention, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	isSourceEvicted := func(workspace, timestamp string) bool {
+		if _, ok := m.log[workspace][timestamp]; ok {
+			return false
+		}
+		w, ok := m.evicted[workspace]
+		return ok && !tsLess(w, timestamp)
+	}
+
+	var evicted int
+	for channelID, logs := range m.reverse {
+		for ts, d := range logs {
+			if isReverseEvicted(ts, d, r, now, isSourceEvicted) {
+				delete(logs, ts)
+				evicted++
+			}
+		}
+		if len(logs) == 0 {
+			delete(m.reverse, channelID)
+		}
+	}
+
+	return evicted, nil
+}
+
+// Sweep evict LogData by retention policy
+func (m *MemoryLogStore) Sweep(r Retention, now time.Time) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	evicted := map[string]int{}
+	for workspace, logs := range m.log {
+		timestamps := make([]string, 0, len(logs))
+		for ts := range logs {
+			timestamps = append(timestamps, ts)
+		}
+		sortTimestamps(timestamps)
+
+		targets := selectEvictTimestamps(timestamps, r, now)
+		if len(targets) == 0 {
+			continue
+		}
+		for _, ts := range targets {
+			delete(logs, ts)
+		}
+		m.evicted[workspace] = targets[len(targets)-1]
+		evicted[workspace] = len(targets)
+	}
+
+	return evicted, nil
+}
+
+// SweepOutbox evict old undelivered events and markers of delivered events
+func (m *MemoryLogStore) SweepOutbox(r Retention, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, items := range m.outbox {
+		var list []OutboxItem
+		for _, item := range items {
+			list = append(list, item)
+		}
+		for _, ts := range selectEvictOutbox(list, r, now) {
+			delete(items, ts)
+		}
+	}
+	for workspace, markers := range m.markers {
+		for ts, at := range markers {
+			if isMarkerExpired(at, now) {
+				delete(markers, ts)
+			}
+		}
+		if len(markers) == 0 {
+			delete(m.markers, workspace)
+		}
+	}
+
+	return nil
+}
+
+// AddOutbox record event
+func (m *MemoryLogStore) AddOutbox(item OutboxItem) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.outbox[item.Workspace]; !ok {
+		m.outbox[item.Workspace] = map[string]OutboxItem{}
+	}
+	if _, ok := m.outbox[item.Workspace][item.Timestamp]; ok {
+		return false, nil
+	}
+	if _, ok := m.markers[item.Workspace][item.Timestamp]; ok {
+		return false, nil
+	}
+	m.outbox[item.Workspace][item.Timestamp] = item
+	return true, nil
+}
+
+// DoneOutbox remove delivered event and keep marker
+func (m *MemoryLogStore) DoneOutbox(workspace, timestamp string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.outbox[workspace], timestamp)
+	if _, ok := m.markers[workspace]; !ok {
+		m.markers[workspace] = map[string]time.Time{}
+	}
+	m.markers[workspace][timestamp] = now
+	return nil
+}
+
+// PendingOutbox get undelivered events
+func (m *MemoryLogStore) PendingOutbox(workspace string) ([]OutboxItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []OutboxItem
+	for _, item := range m.outbox[workspace] {
+		items = append(items, item)
+	}
+	sortOutboxItems(items)
+
+	return items, nil
+}
+
+// Close do nothing
+func (m *MemoryLogStore) Close() error {
+	return nil
+}
+
+// AdvanceCursor set timestamp of channel if it is newer
+func (m *MemoryLogStore) AdvanceCursor(workspace, channelID, timestamp string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.cursors[workspace]; !ok {
+		m.cursors[workspace] = map[string]string{}
+	}
+	if cur, ok := m.cursors[workspace][channelID]; ok && !tsLess(cur, timestamp) {
+		return nil
+	}
+	m.cursors[workspace][channelID] = timestamp
+	return nil
+}
+
+// GetCursors get timestamps of channels
+func (m *MemoryLogStore) GetCursors(workspace string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cursors := map[string]string{}
+	for ch, ts := range m.cursors[workspace] {
+		cursors[ch] = ts
+	}
+	return cursors, nil
+}
+
+// SetFileMapping register FileMapping
+func (m *MemoryLogStore) SetFileMapping(workspace, fileID string, fm FileMapping) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := logKey(workspace, fileID)
+	if _, ok := m.files[key]; !ok {
+		m.files[key] = map[string]FileMapping{}
+	}
+	m.files[key][fm.ChannelID] = fm
+	return nil
+}
+
+// GetFileMapping retrieve FileMapping
+func (m *MemoryLogStore) GetFileMapping(workspace, fileID, aggrChannelID string) (*FileMapping, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	fm, ok := m.files[logKey(workspace, fileID)][aggrChannelID]
+	if !ok {
+		return nil, ErrFileMappingNotFound
+	}
+	return &fm, nil
+}
+
+// ListFileMappings retrieve FileMapping of all aggregated channels
+func (m *MemoryLogStore) ListFileMappings(workspace, fileID string) ([]FileMapping, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var mappings []FileMapping
+	for _, fm := range m.files[logKey(workspace, fileID)] {
+		mappings = append(mappings, fm)
+	}
+	return mappings, nil
+}
+
+// DeleteFileMapping delete FileMapping
+func (m *MemoryLogStore) DeleteFileMapping(workspace, fileID, aggrChannelID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := logKey(workspace, fileID)
+	delete(m.files[key], aggrChannelID)
+	if len(m.files[key]) == 0 {
+		delete(m.files, key)
+	}
+	return nil
+}
+
+// SweepFileMapping evict FileMapping
+func (m *MemoryLogStore) SweepFileMapping(r Retention, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	isSourceEvicted := func(workspace, timestamp string) bool {
+		if _, ok := m.log[workspace][timestamp]; ok {
+			return false
+		}
+		w, ok := m.evicted[workspace]
+		return ok && !tsLess(w, timestamp)
+	}
+
+	var evicted int
+	for key, mappings := range m.files {
+		workspace := strings.SplitN(key, ",", 2)[0]
+		for channelID, fm := range mappings {
+			if isFileMappingEvicted(workspace, fm, r, now, isSourceEvicted) {
+				delete(mappings, channelID)
+				evicted++
+			}
+		}
+		if len(mappings) == 0 {
+			delete(m.files, key)
+		}
+	}
+
+	return evicted, nil
+}
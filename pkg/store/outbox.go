@@ -0,0 +1,110 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Outbox is write-ahead storage of source events, that used to deliver events certainly
+type Outbox interface {
+	// AddOutbox record event before delivering, return false if event is already recorded or delivered
+	AddOutbox(item OutboxItem) (bool, error)
+	// DoneOutbox remove delivered event, and keep only marker of timestamp to deduplicate
+	DoneOutbox(workspace, timestamp string, now time.Time) error
+	// PendingOutbox get undelivered events of workspace, sorted by oldest first
+	PendingOutbox(workspace string) ([]OutboxItem, error)
+	// SweepOutbox evict undelivered events older than max age, and markers older than DeliveredMarkerTTL
+	SweepOutbox(r Retention, now time.Time) error
+}
+
+const (
+	// DeliveredMarkerTTL is duration of keeping marker of delivered event
+	DeliveredMarkerTTL = 24 * time.Hour
+)
+
+// OutboxItem is recorded event
+type OutboxItem struct {
+	Workspace string
+	Timestamp string
+	Channel   string          // id of source channel
+	Event     json.RawMessage // slack.MessageEvent
+	Backfill  bool            // event is got from history after reconnecting
+	CreatedAt time.Time
+}
+
+// MessageEvent decode recorded event
+func (i OutboxItem) MessageEvent() (*slack.MessageEvent, error) {
+	var ev slack.MessageEvent
+	if err := json.Unmarshal(i.Event, &ev); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	return &ev, nil
+}
+
+// AddOutbox record message event before delivering, return false if event is already recorded
+func AddOutbox(workspace string, ev *slack.MessageEvent, backfill bool) (bool, error) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	added, err := GetLogStore().AddOutbox(OutboxItem{
+		Workspace: workspace,
+		Timestamp: ev.Timestamp,
+		Channel:   ev.Channel,
+		Event:     b,
+		Backfill:  backfill,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to add outbox: %w", err)
+	}
+	return added, nil
+}
+
+// DoneOutbox remove delivered event from outbox
+func DoneOutbox(workspace, timestamp string) error {
+	if err := GetLogStore().DoneOutbox(workspace, timestamp, time.Now()); err != nil {
+		return fmt.Errorf("failed to done outbox: %w", err)
+	}
+	return nil
+}
+
+// PendingOutbox get undelivered events of workspace
+func PendingOutbox(workspace string) ([]OutboxItem, error) {
+	items, err := GetLogStore().PendingOutbox(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending outbox: %w", err)
+	}
+	return items, nil
+}
+
+// selectEvictOutbox return timestamps of undelivered events that must be evicted, they are evicted only by max age
+func selectEvictOutbox(items []OutboxItem, r Retention, now time.Time) []string {
+	if r.MaxAge <= 0 {
+		return nil
+	}
+
+	var evicted []string
+	for _, item := range items {
+		if now.Sub(item.CreatedAt) > r.MaxAge {
+			evicted = append(evicted, item.Timestamp)
+		}
+	}
+	return evicted
+}
+
+// isMarkerExpired check marker of delivered event that recorded at must be evicted
+func isMarkerExpired(at, now time.Time) bool {
+	return now.Sub(at) > DeliveredMarkerTTL
+}
+
+func sortOutboxItems(items []OutboxItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return tsLess(items[i].Timestamp, items[j].Timestamp)
+	})
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	testStores(t, func(t *testing.T, s LogStore) {
+		items := []OutboxItem{
+			{Workspace: "team1", Timestamp: "1600000000.000300", Channel: "C01", CreatedAt: testNow},
+			{Workspace: "team1", Timestamp: "1600000000.000100", Channel: "C02", CreatedAt: testNow},
+			{Workspace: "team1", Timestamp: "1600000000.000200", Channel: "C01", CreatedAt: testNow},
+			{Workspace: "team10", Timestamp: "1600000000.000100", Channel: "C01", CreatedAt: testNow},
+		}
+		for _, item := range items {
+			added, err := s.AddOutbox(item)
+			if err != nil || !added {
+				t.Fatalf("failed to add outbox: %v, %+v", added, err)
+			}
+		}
+
+		// recorded event is not added again
+		if added, err := s.AddOutbox(items[0]); err != nil || added {
+			t.Errorf("duplicated event is added: %v, %+v", added, err)
+		}
+
+		pending, err := s.PendingOutbox("team1")
+		if err != nil {
+			t.Fatalf("failed to get pending: %+v", err)
+		}
+		want := []string{"1600000000.000100", "1600000000.000200", "1600000000.000300"}
+		if len(pending) != len(want) {
+			t.Fatalf("unexpected pending events: %+v", pending)
+		}
+		for i, item := range pending {
+			if item.Timestamp != want[i] || item.Workspace != "team1" {
+				t.Errorf("pending events must be sorted by timestamp and filtered by workspace: %+v", pending)
+				break
+			}
+		}
+
+		if err := s.DoneOutbox("team1", "1600000000.000100", testNow); err != nil {
+			t.Fatalf("failed to done outbox: %+v", err)
+		}
+		pending, _ = s.PendingOutbox("team1")
+		if len(pending) != 2 {
+			t.Errorf("delivered event is still pending: %+v", pending)
+		}
+		// marker deduplicate event that is received again (e.g. backfill)
+		if added, err := s.AddOutbox(items[1]); err != nil || added {
+			t.Errorf("delivered event is added again: %v, %+v", added, err)
+		}
+	})
+}
+
+func TestOutboxSweep(t *testing.T) {
+	tests := []struct {
+		name        string
+		r           Retention
+		now         time.Time
+		wantPending int
+		wantReAdded bool // delivered event can be added again, because marker is evicted
+	}{
+		{name: "unlimited", r: Retention{}, now: testNow.Add(time.Hour), wantPending: 2},
+		{name: "max age", r: Retention{MaxAge: 30 * time.Minute}, now: testNow.Add(time.Hour), wantPending: 1},
+		{name: "marker is expired", r: Retention{}, now: testNow.Add(DeliveredMarkerTTL + time.Hour), wantPending: 2, wantReAdded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStores(t, func(t *testing.T, s LogStore) {
+				items := []OutboxItem{
+					{Workspace: "team1", Timestamp: "1600000000.000100", CreatedAt: testNow.Add(-time.Hour)},
+					{Workspace: "team1", Timestamp: "1600000000.000200", CreatedAt: testNow},
+					{Workspace: "team1", Timestamp: "1600000000.000300", CreatedAt: testNow.Add(30 * time.Minute)},
+				}
+				for _, item := range items {
+					if _, err := s.AddOutbox(item); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := s.DoneOutbox("team1", items[0].Timestamp, testNow); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := s.SweepOutbox(tt.r, tt.now); err != nil {
+					t.Fatalf("failed to sweep outbox: %+v", err)
+				}
+
+				pending, err := s.PendingOutbox("team1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(pending) != tt.wantPending {
+					t.Errorf("unexpected pending events: want %d, but %+v", tt.wantPending, pending)
+				}
+				added, err := s.AddOutbox(items[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if added != tt.wantReAdded {
+					t.Errorf("unexpected result of adding delivered event: want %v, but %v", tt.wantReAdded, added)
+				}
+			})
+		})
+	}
+}
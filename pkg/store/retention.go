@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"expvar"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Retention is policy of garbage collection for LogData
+type Retention struct {
+	MaxAge        time.Duration // evict LogData older than MaxAge, 0 is unlimited
+	MaxEntries    int           // max number of LogData per workspace, 0 is unlimited
+	SweepInterval time.Duration
+}
+
+const (
+	// DefaultSweepInterval is default interval of log sweeper
+	DefaultSweepInterval = 10 * time.Minute
+)
+
+var (
+	retention Retention
+
+	// metrics of evictions, published in /debug/vars
+	evictionsVar     = expvar.NewMap("aguri_slack_log_evictions")
+	evictionsTotal   = expvar.NewInt("aguri_slack_log_evictions_total")
+	reverseEvictions = expvar.NewInt("aguri_reverse_log_evictions_total")
+	fileEvictions    = expvar.NewInt("aguri_file_mapping_evictions_total")
+	lastSweepSeconds = expvar.NewFloat("aguri_slack_log_last_sweep_seconds")
+)
+
+// SetLogRetention set retention policy of LogData
+func SetLogRetention(r Retention) {
+	logMu.Lock()
+	defer logMu.Unlock()
+	retention = r
+}
+
+// GetLogRetention get retention policy of LogData
+func GetLogRetention() Retention {
+	logMu.RLock()
+	defer logMu.RUnlock()
+	return retention
+}
+
+// RunLogSweeper evict LogData periodically until ctx is done
+func RunLogSweeper(ctx context.Context) {
+	for {
+		interval := GetLogRetention().SweepInterval
+		if interval <= 0 {
+			interval = DefaultSweepInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if _, err := SweepSlackLog(time.Now()); err != nil {
+			logrus.Warnf("failed to sweep slack log: %+v", err)
+		}
+	}
+}
+
+// SweepSlackLog evict LogData by retention policy, and return number of evicted LogData per workspace
+func SweepSlackLog(now time.Time) (map[string]int, error) {
+	r := GetLogRetention()
+
+	// markers of delivered events are evicted even if retention is unlimited
+	if err := GetLogStore().SweepOutbox(r, now); err != nil {
+		return nil, err
+	}
+
+	if r.MaxAge <= 0 && r.MaxEntries <= 0 {
+		return nil, nil
+	}
+
+	started := time.Now()
+	evicted, err := GetLogStore().Sweep(r, now)
+	lastSweepSeconds.Set(time.Since(started).Seconds())
+	for workspace, n := range evicted {
+		evictionsVar.Add(workspace, int64(n))
+		evictionsTotal.Add(int64(n))
+		logrus.Debugf("evicted %d slack log (workspace: %s)", n, workspace)
+	}
+	if err != nil {
+		return evicted, err
+	}
+
+	// reverse index follow eviction of source LogData, so sweep it after
+	n, err := GetLogStore().SweepReverse(r, now)
+	reverseEvictions.Add(int64(n))
+	if err != nil {
+		return evicted, err
+	}
+	logrus.Debugf("evicted %d reverse slack log", n)
+
+	// mapping of mirrored files also follow eviction of source LogData
+	n, err = GetLogStore().SweepFileMapping(r, now)
+	fileEvictions.Add(int64(n))
+	if err != nil {
+		return evicted, err
+	}
+	logrus.Debugf("evicted %d file mapping", n)
+
+	return evicted, nil
+}
+
+// selectEvictTimestamps return timestamps that must be evicted.
+// timestamps must be sorted by oldest first.
+func selectEvictTimestamps(timestamps []string, r Retention, now time.Time) []string {
+	var n int
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		for n < len(timestamps) && tsToTime(timestamps[n]).Before(cutoff) {
+			n++
+		}
+	}
+	if r.MaxEntries > 0 && len(timestamps)-n > r.MaxEntries {
+		n = len(timestamps) - r.MaxEntries
+	}
+
+	return timestamps[:n]
+}
+
+func sortTimestamps(timestamps []string) {
+	sort.Slice(timestamps, func(i, j int) bool {
+		return tsLess(timestamps[i], timestamps[j])
+	})
+}
+
+// tsLess compare timestamp of slack (e.g. "1634567890.123456")
+func tsLess(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return fa < fb
+}
+
+func tsToTime(ts string) time.Time {
+	f, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9))
+}
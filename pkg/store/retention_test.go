@@ -0,0 +1,162 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testNow = time.Unix(1600100000, 0)
+
+// tsAgo return timestamp of slack that is d before testNow
+func tsAgo(d time.Duration) string {
+	return fmt.Sprintf("%d.000100", testNow.Add(-d).Unix())
+}
+
+func TestSelectEvictTimestamps(t *testing.T) {
+	timestamps := []string{tsAgo(3 * time.Hour), tsAgo(2 * time.Hour), tsAgo(30 * time.Minute), tsAgo(time.Minute)}
+
+	tests := []struct {
+		name string
+		r    Retention
+		want []string
+	}{
+		{name: "unlimited", r: Retention{}, want: []string{}},
+		{name: "max age", r: Retention{MaxAge: time.Hour}, want: timestamps[:2]},
+		{name: "max entries", r: Retention{MaxEntries: 1}, want: timestamps[:3]},
+		{name: "max age is stricter", r: Retention{MaxAge: time.Hour, MaxEntries: 3}, want: timestamps[:2]},
+		{name: "max entries is stricter", r: Retention{MaxAge: 4 * time.Hour, MaxEntries: 2}, want: timestamps[:2]},
+		{name: "nothing is old", r: Retention{MaxAge: 4 * time.Hour, MaxEntries: 10}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectEvictTimestamps(timestamps, tt.r, testNow)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v, but %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLogStoreSweep(t *testing.T) {
+	old, middle, recent := tsAgo(3*time.Hour), tsAgo(2*time.Hour), tsAgo(time.Minute)
+
+	tests := []struct {
+		name        string
+		r           Retention
+		wantEvicted map[string]int
+		wantErr     map[string]error // key: timestamp of team1
+	}{
+		{
+			name:        "unlimited",
+			r:           Retention{},
+			wantEvicted: map[string]int{},
+			wantErr:     map[string]error{old: nil, middle: nil, recent: nil},
+		},
+		{
+			name:        "max age",
+			r:           Retention{MaxAge: time.Hour},
+			wantEvicted: map[string]int{"team1": 2, "team2": 1},
+			wantErr: map[string]error{
+				old:    ErrSlackLogEvicted,
+				middle: ErrSlackLogEvicted,
+				recent: nil,
+				// older than newest evicted timestamp, so it may be evicted
+				tsAgo(4 * time.Hour): ErrSlackLogEvicted,
+				// newer than newest evicted timestamp, so it is never registered
+				tsAgo(30 * time.Minute): ErrSourceChannelNotFound,
+			},
+		},
+		{
+			name:        "max entries",
+			r:           Retention{MaxEntries: 2},
+			wantEvicted: map[string]int{"team1": 1},
+			wantErr:     map[string]error{old: ErrSlackLogEvicted, middle: nil, recent: nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStores(t, func(t *testing.T, s LogStore) {
+				for _, ts := range []string{old, middle, recent} {
+					if err := s.Set("team1", ts, LogData{Body: ts}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				// other workspace is counted separately
+				if err := s.Set("team2", old, LogData{Body: old}); err != nil {
+					t.Fatal(err)
+				}
+
+				evicted, err := s.Sweep(tt.r, testNow)
+				if err != nil {
+					t.Fatalf("failed to sweep: %+v", err)
+				}
+				if !reflect.DeepEqual(evicted, tt.wantEvicted) {
+					t.Errorf("unexpected evicted: want %v, but %v", tt.wantEvicted, evicted)
+				}
+
+				for ts, wantErr := range tt.wantErr {
+					got, err := s.Get("team1", ts)
+					if !errors.Is(err, wantErr) {
+						t.Errorf("unexpected error of %s: want %v, but %v", ts, wantErr, err)
+					}
+					if wantErr == nil && (got == nil || got.Body != ts) {
+						t.Errorf("unexpected LogData of %s: %+v", ts, got)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestLogStoreSweepReverse(t *testing.T) {
+	old, recent := tsAgo(3*time.Hour), tsAgo(time.Minute)
+
+	testStores(t, func(t *testing.T, s LogStore) {
+		for _, ts := range []string{old, recent} {
+			if err := s.Set("team1", ts, LogData{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		reverse := map[string]LogData{
+			"1": {Workspace: "team1", SourceTimestamp: old},    // source is evicted
+			"2": {Workspace: "team1", SourceTimestamp: recent}, // source is kept
+			"3": {Channel: "general"},                          // source is unknown, kept until max age
+		}
+		for key, d := range reverse {
+			if err := s.SetReverse("CA1", fmt.Sprintf("%d.00000%s", testNow.Unix(), key), d); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		r := Retention{MaxEntries: 1}
+		if _, err := s.Sweep(r, testNow); err != nil {
+			t.Fatal(err)
+		}
+		n, err := s.SweepReverse(r, testNow)
+		if err != nil {
+			t.Fatalf("failed to sweep reverse: %+v", err)
+		}
+		if n != 1 {
+			t.Errorf("unexpected number of evicted reverse LogData: %d", n)
+		}
+		for key, wantErr := range map[string]error{"1": ErrSourceChannelNotFound, "2": nil, "3": nil} {
+			if _, err := s.GetReverse("CA1", fmt.Sprintf("%d.00000%s", testNow.Unix(), key)); !errors.Is(err, wantErr) {
+				t.Errorf("unexpected error of reverse %s: want %v, but %v", key, wantErr, err)
+			}
+		}
+
+		// all reverse LogData is older than max age
+		n, err = s.SweepReverse(Retention{MaxAge: time.Hour}, testNow.Add(2*time.Hour))
+		if err != nil {
+			t.Fatalf("failed to sweep reverse: %+v", err)
+		}
+		if n != 2 {
+			t.Errorf("unexpected number of evicted reverse LogData by max age: %d", n)
+		}
+	})
+}
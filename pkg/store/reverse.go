@@ -0,0 +1,44 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReverseIndex is storage of LogData keyed by aggregated message, separated from LogData of source workspaces
+type ReverseIndex interface {
+	// SetReverse register LogData to key of aggregated channel id and timestamp
+	SetReverse(aggrChannelID, timestamp string, data LogData) error
+	// GetReverse retrieve LogData by aggregated channel id and timestamp
+	GetReverse(aggrChannelID, timestamp string) (*LogData, error)
+	// SweepReverse evict LogData that is older than max age or that source LogData is already evicted,
+	// and return number of evicted LogData. It must be called after Sweep.
+	SweepReverse(r Retention, now time.Time) (int, error)
+}
+
+// PutReverseSlackLog set LogData keyed by aggregated message
+func PutReverseSlackLog(aggrChannelID, timestamp string, d LogData) error {
+	if err := GetLogStore().SetReverse(aggrChannelID, timestamp, d); err != nil {
+		return fmt.Errorf("failed to set reverse slack log: %w", err)
+	}
+
+	return nil
+}
+
+// GetReverseSlackLog get LogData by aggregated message
+func GetReverseSlackLog(aggrChannelID, timestamp string) (*LogData, error) {
+	return GetLogStore().GetReverse(aggrChannelID, timestamp)
+}
+
+// isReverseEvicted check LogData of reverse index must be evicted.
+// isSourceEvicted report source LogData of workspace and timestamp is already evicted.
+func isReverseEvicted(timestamp string, d LogData, r Retention, now time.Time, isSourceEvicted func(workspace, timestamp string) bool) bool {
+	if r.MaxAge > 0 && tsToTime(timestamp).Before(now.Add(-r.MaxAge)) {
+		return true
+	}
+	if d.Workspace == "" || d.SourceTimestamp == "" {
+		// source is unknown (e.g. parsed from username), evicted only by max age
+		return false
+	}
+	return isSourceEvicted(d.Workspace, d.SourceTimestamp)
+}
@@ -3,47 +3,131 @@ package store
 import (
 	"fmt"
 	"strings"
-)
-
-var (
-	log = map[string]LogData{} // key: "workspace,timestamp"
+	"sync"
+	"time"
 )
 
 // LogData is format of logging
 type LogData struct {
+	Workspace      string // name of source workspace, set in ReverseIndex
 	Channel        string
 	Body           string
 	ToAPIChannelID string
 	ToAPITimestamp string
+
+	ToAPIExtraTimestamps []string // other aggregated messages of same source message (e.g. attachments)
+
+	SourceChannelID       string // id of source channel, empty in LogData that stored by old version
+	SourceTimestamp       string // timestamp of source message
+	SourceThreadTimestamp string // timestamp of parent message if source message is reply in thread
+
+	ReactionNotes map[string][]string // timestamps of notes in thread, keyed by name of emoji that is not exist in aggregated slack
+}
+
+// SourceThread return timestamp of source thread that reply is posted to
+func (d LogData) SourceThread() string {
+	if d.SourceThreadTimestamp != "" {
+		return d.SourceThreadTimestamp
+	}
+	return d.SourceTimestamp
+}
+
+// LogStore is storage of LogData
+type LogStore interface {
+	ReverseIndex
+	Outbox
+	CursorStore
+	FileStore
+
+	// Set register LogData to key of workspace and timestamp
+	Set(workspace, timestamp string, data LogData) error
+	// Get retrieve LogData by workspace and timestamp
+	Get(workspace, timestamp string) (*LogData, error)
+	// Sweep evict LogData by retention policy, and return number of evicted LogData per workspace
+	Sweep(r Retention, now time.Time) (map[string]int, error)
+	// Close release resources of store
+	Close() error
 }
 
+const (
+	// LogStoreTypeMemory is type of LogStore that store in memory
+	LogStoreTypeMemory = "memory"
+	// LogStoreTypeBolt is type of LogStore that store in BoltDB file
+	LogStoreTypeBolt = "bolt"
+)
+
+var (
+	logStore LogStore = NewMemoryLogStore()
+	logMu    sync.RWMutex
+)
+
 var (
 	// ErrSourceChannelNotFound is error message for source channel is not found
 	ErrSourceChannelNotFound = fmt.Errorf("source channel is not found")
+	// ErrSlackLogEvicted is error message for LogData is already evicted by retention policy
+	ErrSlackLogEvicted = fmt.Errorf("slack log is already evicted")
 )
 
-// SetSlackLog set logging to memory
-func SetSlackLog(workspace, timestamp, channelName, text, toAPIChannelID, toAPITimestamp string) {
-	// register post to kv
-	k := strings.Join([]string{workspace, timestamp}, ",")
+// NewLogStore create LogStore by type
+func NewLogStore(storeType, path string) (LogStore, error) {
+	switch storeType {
+	case "", LogStoreTypeMemory:
+		return NewMemoryLogStore(), nil
+	case LogStoreTypeBolt:
+		s, err := NewBoltLogStore(path)
+		if err != nil {
+			// don't return typed nil
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported store type: %s", storeType)
+	}
+}
+
+// SetLogStore set LogStore used by SetSlackLog and GetSlackLog
+func SetLogStore(s LogStore) {
+	logMu.Lock()
+	defer logMu.Unlock()
+	logStore = s
+}
+
+// GetLogStore get LogStore
+func GetLogStore() LogStore {
+	logMu.RLock()
+	defer logMu.RUnlock()
+	return logStore
+}
+
+// CloseLogStore close current LogStore
+func CloseLogStore() error {
+	return GetLogStore().Close()
+}
+
+func logKey(workspace, timestamp string) string {
+	return strings.Join([]string{workspace, timestamp}, ",")
+}
 
-	// TODO: gc
-	log[k] = LogData{
+// SetSlackLog set logging to store
+func SetSlackLog(workspace, timestamp, channelName, text, toAPIChannelID, toAPITimestamp string) error {
+	return PutSlackLog(workspace, timestamp, LogData{
 		Channel:        channelName,
 		Body:           text,
 		ToAPIChannelID: toAPIChannelID,
 		ToAPITimestamp: toAPITimestamp,
-	}
+	})
 }
 
-// GetSlackLog get logging from memory
-func GetSlackLog(workspace, timestamp string) (*LogData, error) {
-	parent := strings.Join([]string{workspace, timestamp}, ",")
-	val, ok := log[parent]
-	if ok == false {
-		// TODO: if can't get channel name, search old message using slack API
-		return nil, ErrSourceChannelNotFound
+// PutSlackLog set LogData to store
+func PutSlackLog(workspace, timestamp string, d LogData) error {
+	if err := GetLogStore().Set(workspace, timestamp, d); err != nil {
+		return fmt.Errorf("failed to set slack log: %w", err)
 	}
 
-	return &val, nil
+	return nil
+}
+
+// GetSlackLog get logging from store
+func GetSlackLog(workspace, timestamp string) (*LogData, error) {
+	return GetLogStore().Get(workspace, timestamp)
 }
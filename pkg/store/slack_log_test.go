@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testStores run f with each type of LogStore, so that they behave the same
+func testStores(t *testing.T, f func(t *testing.T, s LogStore)) {
+	t.Helper()
+
+	t.Run(LogStoreTypeMemory, func(t *testing.T) {
+		f(t, NewMemoryLogStore())
+	})
+	t.Run(LogStoreTypeBolt, func(t *testing.T) {
+		s, err := NewBoltLogStore(filepath.Join(t.TempDir(), "aguri.db"))
+		if err != nil {
+			t.Fatalf("failed to create bolt store: %+v", err)
+		}
+		defer s.Close()
+		f(t, s)
+	})
+}
+
+func TestLogStoreSetGet(t *testing.T) {
+	d := LogData{
+		Channel:               "general",
+		Body:                  "hello",
+		ToAPIChannelID:        "CA1",
+		ToAPITimestamp:        "1600000001.000100",
+		ToAPIExtraTimestamps:  []string{"1600000001.000200"},
+		SourceChannelID:       "C01",
+		SourceTimestamp:       "1600000000.000100",
+		SourceThreadTimestamp: "1600000000.000000",
+		ReactionNotes:         map[string][]string{"party": {"1600000002.000100"}},
+	}
+
+	tests := []struct {
+		name      string
+		workspace string
+		timestamp string
+		want      *LogData
+		wantErr   error
+	}{
+		{name: "found", workspace: "team1", timestamp: "1600000000.000100", want: &d},
+		{name: "other timestamp", workspace: "team1", timestamp: "1600000000.000200", wantErr: ErrSourceChannelNotFound},
+		{name: "other workspace", workspace: "team2", timestamp: "1600000000.000100", wantErr: ErrSourceChannelNotFound},
+	}
+
+	testStores(t, func(t *testing.T, s LogStore) {
+		if err := s.Set("team1", "1600000000.000100", d); err != nil {
+			t.Fatalf("failed to set: %+v", err)
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := s.Get(tt.workspace, tt.timestamp)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("unexpected error: want %v, but %v", tt.wantErr, err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("unexpected LogData: want %+v, but %+v", tt.want, got)
+				}
+			})
+		}
+	})
+}
+
+func TestLogStoreOverwrite(t *testing.T) {
+	testStores(t, func(t *testing.T, s LogStore) {
+		if err := s.Set("team1", "1600000000.000100", LogData{Body: "before"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Set("team1", "1600000000.000100", LogData{Body: "after"}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.Get("team1", "1600000000.000100")
+		if err != nil {
+			t.Fatalf("failed to get: %+v", err)
+		}
+		if got.Body != "after" {
+			t.Errorf("LogData is not overwritten: %+v", got)
+		}
+	})
+}
+
+func TestLogStoreReverse(t *testing.T) {
+	d := LogData{Workspace: "team1", Channel: "general", SourceTimestamp: "1600000000.000100"}
+
+	testStores(t, func(t *testing.T, s LogStore) {
+		if err := s.SetReverse("CA1", "1600000001.000100", d); err != nil {
+			t.Fatalf("failed to set reverse: %+v", err)
+		}
+
+		got, err := s.GetReverse("CA1", "1600000001.000100")
+		if err != nil {
+			t.Fatalf("failed to get reverse: %+v", err)
+		}
+		if !reflect.DeepEqual(*got, d) {
+			t.Errorf("unexpected LogData: want %+v, but %+v", d, *got)
+		}
+
+		// separated from LogData of source workspaces
+		if _, err := s.Get("team1", "1600000001.000100"); !errors.Is(err, ErrSourceChannelNotFound) {
+			t.Errorf("reverse index must not be found as source: %v", err)
+		}
+		if _, err := s.GetReverse("CA2", "1600000001.000100"); !errors.Is(err, ErrSourceChannelNotFound) {
+			t.Errorf("unexpected error of other channel: %v", err)
+		}
+	})
+}
+
+func TestBoltLogStorePersistent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aguri.db")
+
+	s, err := NewBoltLogStore(path)
+	if err != nil {
+		t.Fatalf("failed to create bolt store: %+v", err)
+	}
+	if err := s.Set("team1", "1600000000.000100", LogData{Body: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetReverse("CA1", "1600000001.000100", LogData{Workspace: "team1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen, like restarting aguri
+	s, err = NewBoltLogStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen bolt store: %+v", err)
+	}
+	defer s.Close()
+
+	if got, err := s.Get("team1", "1600000000.000100"); err != nil || got.Body != "hello" {
+		t.Errorf("LogData is not kept: %+v, %v", got, err)
+	}
+	if got, err := s.GetReverse("CA1", "1600000001.000100"); err != nil || got.Workspace != "team1" {
+		t.Errorf("reverse index is not kept: %+v, %v", got, err)
+	}
+}
+
+func TestNewLogStore(t *testing.T) {
+	tests := []struct {
+		storeType string
+		path      string
+		wantErr   bool
+	}{
+		{storeType: "", wantErr: false},
+		{storeType: LogStoreTypeMemory, wantErr: false},
+		{storeType: LogStoreTypeBolt, path: "aguri.db", wantErr: false},
+		{storeType: LogStoreTypeBolt, path: "", wantErr: true},
+		{storeType: "redis", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.storeType, func(t *testing.T) {
+			path := tt.path
+			if path != "" {
+				path = filepath.Join(t.TempDir(), path)
+			}
+			s, err := NewLogStore(tt.storeType, path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s != nil {
+				s.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// rawBlock is Block that is already encoded
+type rawBlock struct {
+	blockType slack.MessageBlockType
+	raw       json.RawMessage
+}
+
+// BlockType return type of block
+func (b rawBlock) BlockType() slack.MessageBlockType {
+	return b.blockType
+}
+
+// MarshalJSON return encoded block
+func (b rawBlock) MarshalJSON() ([]byte, error) {
+	return b.raw, nil
+}
+
+// ConvertBlocks convert blocks of source message to blocks that can be posted to aggregated slack.
+// interactive elements are converted to text, and mentions are converted to name.
+// return nil if blocks are only rich text, because it is same as text of message.
+func ConvertBlocks(ctx context.Context, fromAPI *slack.Client, workspace string, blocks slack.Blocks) ([]slack.Block, error) {
+	onlyRichText := true
+	for _, b := range blocks.BlockSet {
+		if b.BlockType() != slack.MBTRichText {
+			onlyRichText = false
+			break
+		}
+	}
+	if onlyRichText {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(blocks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal blocks: %w", err)
+	}
+	var raws []map[string]interface{}
+	if err := json.Unmarshal(b, &raws); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal blocks: %w", err)
+	}
+
+	c := blockConverter{ctx: ctx, api: fromAPI, workspace: workspace}
+	var converted []slack.Block
+	for _, raw := range raws {
+		block := c.convertBlock(raw)
+		if block == nil {
+			continue
+		}
+		c.convertTexts(block)
+
+		b, err := json.Marshal(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal block: %w", err)
+		}
+		t, _ := block["type"].(string)
+		converted = append(converted, rawBlock{blockType: slack.MessageBlockType(t), raw: b})
+	}
+
+	return converted, nil
+}
+
+const (
+	// maxSectionTextLength is max length of text in section block
+	maxSectionTextLength = 3000
+)
+
+// prependTextBlock prepend section block of text, that is shown instead of text of message if blocks exist
+func prependTextBlock(text string, blocks []slack.Block) []slack.Block {
+	if r := []rune(text); len(r) > maxSectionTextLength {
+		text = string(r[:maxSectionTextLength-3]) + "..."
+	}
+	section := slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)
+	return append([]slack.Block{section}, blocks...)
+}
+
+type blockConverter struct {
+	ctx       context.Context
+	api       *slack.Client
+	workspace string
+}
+
+// convertBlock convert interactive block to non-interactive block, return nil if block can't be posted
+func (c blockConverter) convertBlock(block map[string]interface{}) map[string]interface{} {
+	switch block["type"] {
+	case "actions":
+		var texts []string
+		elements, _ := block["elements"].([]interface{})
+		for _, e := range elements {
+			if el, ok := e.(map[string]interface{}); ok {
+				if text := describeElement(el); text != "" {
+					texts = append(texts, text)
+				}
+			}
+		}
+		if len(texts) == 0 {
+			return nil
+		}
+		return map[string]interface{}{
+			"type":     "context",
+			"elements": []interface{}{mrkdwnText(strings.Join(texts, " | "))},
+		}
+	case "input":
+		label := textOf(block["label"])
+		el, _ := block["element"].(map[string]interface{})
+		return map[string]interface{}{
+			"type": "section",
+			"text": mrkdwnText(strings.TrimSpace("*" + label + "* " + describeElement(el))),
+		}
+	case "file":
+		// file of source slack can't be referred
+		return nil
+	case "section":
+		accessory, ok := block["accessory"].(map[string]interface{})
+		if !ok || accessory["type"] == "image" {
+			return block
+		}
+		delete(block, "accessory")
+		if text := describeElement(accessory); text != "" {
+			if t := textOf(block["text"]); t != "" {
+				text = t + "\n" + text
+			}
+			block["text"] = mrkdwnText(text)
+		}
+		return block
+	default:
+		return block
+	}
+}
+
+// convertTexts convert mentions in text objects and rich text elements
+func (c blockConverter) convertTexts(v interface{}) {
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		switch obj["type"] {
+		case "mrkdwn", "plain_text":
+			if text, ok := obj["text"].(string); ok {
+				obj["text"] = RewriteMrkdwn(c.ctx, c.api, c.workspace, text)
+			}
+			return
+		case "user":
+			id, _ := obj["user_id"].(string)
+			c.replaceElement(obj, fmt.Sprintf("@%s (%s)", userName(c.ctx, c.api, id), c.workspace))
+			return
+		case "channel":
+			if id, ok := obj["channel_id"].(string); ok {
+				c.replaceElement(obj, fmt.Sprintf("#%s (%s)", channelName(c.ctx, c.api, id), c.workspace))
+			}
+			return
+		case "usergroup":
+			id, _ := obj["usergroup_id"].(string)
+			c.replaceElement(obj, fmt.Sprintf("@%s (%s)", userGroupName(c.ctx, c.api, id), c.workspace))
+			return
+		case "broadcast":
+			r, _ := obj["range"].(string)
+			// text element is not mention
+			c.replaceElement(obj, "@"+r)
+			return
+		}
+		for _, child := range obj {
+			c.convertTexts(child)
+		}
+	case []interface{}:
+		for _, child := range obj {
+			c.convertTexts(child)
+		}
+	}
+}
+
+// replaceElement replace rich text element to text element
+func (c blockConverter) replaceElement(obj map[string]interface{}, text string) {
+	for k := range obj {
+		delete(obj, k)
+	}
+	obj["type"] = "text"
+	obj["text"] = text
+}
+
+// describeElement return text that describe interactive element
+func describeElement(el map[string]interface{}) string {
+	if el == nil {
+		return ""
+	}
+	t, _ := el["type"].(string)
+	switch t {
+	case "button":
+		text := textOf(el["text"])
+		if url, ok := el["url"].(string); ok && url != "" {
+			return fmt.Sprintf("<%s|%s>", url, text)
+		}
+		return "[" + text + "]"
+	case "overflow":
+		return ""
+	case "datepicker":
+		if d, ok := el["initial_date"].(string); ok {
+			return "[date: " + d + "]"
+		}
+	case "timepicker":
+		if d, ok := el["initial_time"].(string); ok {
+			return "[time: " + d + "]"
+		}
+	case "checkboxes", "radio_buttons":
+		var options []string
+		list, _ := el["options"].([]interface{})
+		for _, o := range list {
+			if opt, ok := o.(map[string]interface{}); ok {
+				options = append(options, textOf(opt["text"]))
+			}
+		}
+		return "[" + strings.Join(options, ", ") + "]"
+	}
+	if placeholder := textOf(el["placeholder"]); placeholder != "" {
+		return "[" + placeholder + "]"
+	}
+	return "[" + t + "]"
+}
+
+func textOf(v interface{}) string {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	text, _ := obj["text"].(string)
+	return text
+}
+
+func mrkdwnText(text string) map[string]interface{} {
+	return map[string]interface{}{"type": "mrkdwn", "text": text}
+}
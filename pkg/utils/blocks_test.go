@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestConvertBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks string
+		want   string // JSON of converted blocks, "null" if blocks are not needed
+	}{
+		{
+			name:   "no blocks",
+			blocks: `[]`,
+			want:   `null`,
+		},
+		{
+			name:   "only rich text",
+			blocks: `[{"type": "rich_text", "block_id": "b1", "elements": [{"type": "rich_text_section", "elements": [{"type": "text", "text": "hello"}]}]}]`,
+			want:   `null`,
+		},
+		{
+			name:   "mentions in text object",
+			blocks: `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "hi <@U01>, see <#C01> <!here>"}}]`,
+			want:   `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "hi @alice (team1), see #general (team1) ` + "`@here`" + `"}}]`,
+		},
+		{
+			name:   "actions are converted to context",
+			blocks: `[{"type": "actions", "block_id": "b1", "elements": [{"type": "button", "action_id": "a1", "text": {"type": "plain_text", "text": "Approve"}}, {"type": "button", "action_id": "a2", "text": {"type": "plain_text", "text": "Open"}, "url": "https://example.com"}, {"type": "overflow", "action_id": "a3", "options": [{"text": {"type": "plain_text", "text": "x"}, "value": "x"}]}]}]`,
+			want:   `[{"type": "context", "elements": [{"type": "mrkdwn", "text": "[Approve] | <https://example.com|Open>"}]}]`,
+		},
+		{
+			name:   "actions without describable element are dropped",
+			blocks: `[{"type": "actions", "block_id": "b1", "elements": [{"type": "overflow", "action_id": "a1", "options": [{"text": {"type": "plain_text", "text": "x"}, "value": "x"}]}]}]`,
+			want:   `null`,
+		},
+		{
+			name:   "accessory of section is converted to text",
+			blocks: `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "Deploy?"}, "accessory": {"type": "datepicker", "action_id": "a1", "initial_date": "2020-09-13"}}]`,
+			want:   `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "Deploy?\n[date: 2020-09-13]"}}]`,
+		},
+		{
+			name:   "image accessory is kept",
+			blocks: `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "logo"}, "accessory": {"type": "image", "image_url": "https://example.com/a.png", "alt_text": "a"}}]`,
+			want:   `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "logo"}, "accessory": {"type": "image", "image_url": "https://example.com/a.png", "alt_text": "a"}}]`,
+		},
+		{
+			name:   "input is converted to section",
+			blocks: `[{"type": "input", "block_id": "b1", "label": {"type": "plain_text", "text": "Reason"}, "element": {"type": "plain_text_input", "action_id": "a1", "placeholder": {"type": "plain_text", "text": "why?"}}}]`,
+			want:   `[{"type": "section", "text": {"type": "mrkdwn", "text": "*Reason* [why?]"}}]`,
+		},
+		{
+			name:   "file is dropped",
+			blocks: `[{"type": "file", "block_id": "b1", "external_id": "f1", "source": "remote"}, {"type": "divider", "block_id": "b2"}]`,
+			want:   `[{"type": "divider", "block_id": "b2"}]`,
+		},
+		{
+			name:   "rich text with other blocks",
+			blocks: `[{"type": "rich_text", "block_id": "b1", "elements": [{"type": "rich_text_section", "elements": [{"type": "user", "user_id": "U01"}, {"type": "text", "text": " hi"}]}]}, {"type": "divider", "block_id": "b2"}]`,
+			want:   `[{"type": "rich_text", "block_id": "b1", "elements": [{"type": "rich_text_section", "elements": [{"type": "text", "text": "@alice (team1)"}, {"type": "text", "text": " hi"}]}]}, {"type": "divider", "block_id": "b2"}]`,
+		},
+		{
+			name:   "unknown mention is converted to id",
+			blocks: `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "<@U99> <!subteam^S01>"}}]`,
+			want:   `[{"type": "section", "block_id": "b1", "text": {"type": "mrkdwn", "text": "@U99 (team1) @devs (team1)"}}]`,
+		},
+	}
+
+	_, api := newTestSourceSlack(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var blocks slack.Blocks
+			if err := json.Unmarshal([]byte(tt.blocks), &blocks); err != nil {
+				t.Fatalf("failed to unmarshal blocks: %+v", err)
+			}
+
+			converted, err := ConvertBlocks(context.Background(), api, "team1", blocks)
+			if err != nil {
+				t.Fatalf("failed to convert blocks: %+v", err)
+			}
+
+			b, err := json.Marshal(converted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("want %s, but %s", tt.want, b)
+			}
+		})
+	}
+}
+
+func TestPrependTextBlock(t *testing.T) {
+	long := make([]rune, maxSectionTextLength+10)
+	for i := range long {
+		long[i] = 'あ'
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "short", text: "> quote\nhello", want: "> quote\nhello"},
+		{name: "too long", text: string(long), want: string(long[:maxSectionTextLength-3]) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := prependTextBlock(tt.text, []slack.Block{slack.NewDividerBlock()})
+			if len(blocks) != 2 {
+				t.Fatalf("unexpected blocks: %+v", blocks)
+			}
+			section, ok := blocks[0].(*slack.SectionBlock)
+			if !ok || section.Text.Text != tt.want {
+				t.Errorf("unexpected section: %+v", blocks[0])
+			}
+		})
+	}
+}
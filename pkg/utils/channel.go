@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackutilsx"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/store"
+)
+
+const (
+	// minRefreshInterval is min interval of refreshing mapping of aggregated channels by miss
+	minRefreshInterval = 1 * time.Minute
+)
+
+var (
+	errChannelNameTaken = fmt.Errorf("name of channel is already taken")
+
+	lastRefreshed   time.Time
+	lastRefreshedMu sync.Mutex
+)
+
+// RefreshAggrChannels retrieve id of aggregated channels, and set mapping of channel id to source workspace
+func RefreshAggrChannels(ctx context.Context, toAPI *slack.Client) error {
+	lastRefreshedMu.Lock()
+	lastRefreshed = time.Now()
+	lastRefreshedMu.Unlock()
+
+	channels, err := GetConversationsList(ctx, toAPI, []slackutilsx.ChannelType{slackutilsx.CTypeChannel, slackutilsx.CTypeGroup})
+	if err != nil {
+		return fmt.Errorf("failed to get conversation list: %w", err)
+	}
+	ids := map[string]string{} // key: channel name
+	private := map[string]bool{}
+	for _, c := range channels {
+		ids[c.Name] = c.ID
+		private[c.Name] = c.IsPrivate
+	}
+
+	var aggrChannels []store.AggrChannel
+	for workspace := range config.GetConfig().From {
+		name := config.GetToChannelName(workspace)
+		if id, ok := ids[name]; ok {
+			aggrChannels = append(aggrChannels, store.AggrChannel{ID: id, Name: name, Workspace: workspace})
+		}
+	}
+	for name, workspaces := range dmChannels() {
+		id, ok := ids[name]
+		if !ok {
+			continue
+		}
+		if !private[name] {
+			logrus.Warnf("#%s is public channel, direct messages from %s are not posted to it until it is made private", name, strings.Join(workspaces, ", "))
+		}
+		c := store.AggrChannel{ID: id, Name: name}
+		if len(workspaces) == 1 {
+			// shared channel is resolved by thread
+			c.Workspace = workspaces[0]
+		}
+		aggrChannels = append(aggrChannels, c)
+	}
+	dmRoutes := dmRouteDestinations()
+	for _, name := range config.GetRouteDestinations() {
+		id, ok := ids[name]
+		if !ok {
+			continue
+		}
+		if !private[name] && dmRoutes[name] {
+			logrus.Warnf("#%s is public channel, direct messages routed to it are not posted until it is made private", name)
+		}
+		aggrChannels = append(aggrChannels, store.AggrChannel{ID: id, Name: name})
+	}
+	store.SetAggrChannels(aggrChannels)
+
+	return nil
+}
+
+// ResolveAggrChannel get aggregated channel by channel id.
+// If channel is not found, refresh mapping because channel may be created after refreshed.
+func ResolveAggrChannel(ctx context.Context, toAPI *slack.Client, channelID string) (store.AggrChannel, bool, error) {
+	if c, ok := store.GetAggrChannel(channelID); ok {
+		return c, true, nil
+	}
+
+	lastRefreshedMu.Lock()
+	needRefresh := time.Since(lastRefreshed) > minRefreshInterval
+	lastRefreshedMu.Unlock()
+	if !needRefresh {
+		return store.AggrChannel{}, false, nil
+	}
+
+	if err := RefreshAggrChannels(ctx, toAPI); err != nil {
+		return store.AggrChannel{}, false, err
+	}
+	c, ok := store.GetAggrChannel(channelID)
+	return c, ok, nil
+}
+
+// EnsureAggrChannels create aggregated channels that not exist, and return names of created channels
+func EnsureAggrChannels(ctx context.Context, toAPI *slack.Client) ([]string, error) {
+	to := config.GetTo()
+	if !to.CreateChannels {
+		return nil, nil
+	}
+
+	channels, err := GetConversationsList(ctx, toAPI, []slackutilsx.ChannelType{slackutilsx.CTypeChannel, slackutilsx.CTypeGroup})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation list: %w", err)
+	}
+	exists := map[string]bool{}
+	for _, c := range channels {
+		exists[c.Name] = true
+	}
+
+	type target struct {
+		name    string
+		private bool
+		purpose string
+	}
+	var targets []target
+	for workspace, from := range config.GetConfig().From {
+		private := to.PrivateChannels
+		if from.Private != nil {
+			private = *from.Private
+		}
+		targets = append(targets, target{
+			name:    config.GetToChannelName(workspace),
+			private: private,
+			purpose: fmt.Sprintf("Aggregated messages from %s by aguri", workspace),
+		})
+	}
+	for name, workspaces := range dmChannels() {
+		targets = append(targets, target{
+			name:    name,
+			private: true,
+			purpose: fmt.Sprintf("Aggregated direct messages from %s by aguri", strings.Join(workspaces, ", ")),
+		})
+	}
+	dmRoutes := dmRouteDestinations()
+	for _, name := range config.GetRouteDestinations() {
+		targets = append(targets, target{
+			name:    name,
+			private: to.PrivateChannels || dmRoutes[name],
+			purpose: "Aggregated messages by routing of aguri",
+		})
+	}
+
+	var created []string
+	var errs []string
+	for _, t := range targets {
+		if exists[t.name] {
+			continue
+		}
+		// mark as exist even if failed, targets may have same name
+		exists[t.name] = true
+
+		err := createAggrChannel(ctx, toAPI, t.name, t.purpose, t.private, to.Invite)
+		switch {
+		case errors.Is(err, errChannelNameTaken):
+			// list of conversations don't include archived channels and private channels that aguri is not member of
+			logrus.Warnf("#%s is not created because the name is already taken (archived, or private and aguri is not a member), unarchive it or invite aguri", t.name)
+			continue
+		case err != nil:
+			// continue to create other channels
+			errs = append(errs, err.Error())
+			continue
+		}
+		created = append(created, t.name)
+	}
+	if len(errs) != 0 {
+		return created, fmt.Errorf("failed to create %d channels: %s", len(errs), strings.Join(errs, ", "))
+	}
+
+	return created, nil
+}
+
+// isSharedDestination check aggregated channel may be shared by some workspaces
+func isSharedDestination(workspace, aggrChannelName string) bool {
+	switch aggrChannelName {
+	case config.GetToChannelName(workspace):
+		return false
+	case config.GetDMChannelName(workspace):
+		return len(dmChannels()[aggrChannelName]) > 1
+	default:
+		return true
+	}
+}
+
+// dmChannels return names of channels for DM and MPIM, and workspaces that use it
+func dmChannels() map[string][]string {
+	channels := map[string][]string{}
+	for workspace := range config.GetConfig().From {
+		if name := config.GetDMChannelName(workspace); name != "" {
+			channels[name] = append(channels[name], workspace)
+		}
+	}
+	for _, workspaces := range channels {
+		sort.Strings(workspaces)
+	}
+	return channels
+}
+
+// dmRouteDestinations return names of destination channels of routes that may match DM and MPIM
+func dmRouteDestinations() map[string]bool {
+	names := map[string]bool{}
+	for _, name := range config.GetDMRouteDestinations() {
+		names[name] = true
+	}
+	return names
+}
+
+func createAggrChannel(ctx context.Context, toAPI *slack.Client, name, purpose string, private bool, invite []string) error {
+	ch, err := toAPI.CreateConversationContext(ctx, name, private)
+	if err != nil && err.Error() == "name_taken" {
+		return errChannelNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create channel %s: %w", name, err)
+	}
+	if _, err := toAPI.SetTopicOfConversationContext(ctx, ch.ID, purpose); err != nil {
+		return fmt.Errorf("failed to set topic of %s: %w", name, err)
+	}
+	if _, err := toAPI.SetPurposeOfConversationContext(ctx, ch.ID, purpose); err != nil {
+		return fmt.Errorf("failed to set purpose of %s: %w", name, err)
+	}
+	if len(invite) != 0 {
+		if _, err := toAPI.InviteUsersToConversationContext(ctx, ch.ID, invite...); err != nil {
+			return fmt.Errorf("failed to invite users to %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext is context that keep values of parent but is never canceled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// WithoutCancel return context that is not canceled when parent is canceled.
+// It is used for handling message that already received, so that shutdown do not interrupt posting.
+func WithoutCancel(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+type backfillKey struct{}
+
+// WithBackfill return context that mark message is backfilled after reconnecting
+func WithBackfill(parent context.Context) context.Context {
+	return context.WithValue(parent, backfillKey{}, true)
+}
+
+// IsBackfill check message is backfilled
+func IsBackfill(ctx context.Context) bool {
+	b, _ := ctx.Value(backfillKey{}).(bool)
+	return b
+}
@@ -3,14 +3,21 @@ package utils
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackutilsx"
+	"github.com/whywaita/aguri/pkg/config"
 )
 
 const (
 	// ErrMethodNotSupportedForChannelType is error message for method_not_supported_for_channel_type
 	ErrMethodNotSupportedForChannelType = "method_not_supported_for_channel_type"
+
+	// ChannelTypeMPIM is type of multi-person direct message, that is not defined in slackutilsx
+	ChannelTypeMPIM = "MPIM"
 )
 
 // GetConversationsList get list of conversation
@@ -74,7 +81,7 @@ func concat(xs, ys []slack.Channel) []slack.Channel {
 
 // ConvertDisplayPrivateChannel retrieve channel name
 func ConvertDisplayPrivateChannel(ctx context.Context, api *slack.Client, channelID string) (string, error) {
-	channels, err := GetConversationsList(ctx, api, []slackutilsx.ChannelType{slackutilsx.CTypeGroup})
+	channels, err := GetDirectory(api).GetChannels(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -95,10 +102,10 @@ func ConvertDisplayChannelName(ctx context.Context, api *slack.Client, ev *slack
 	channelType := slackutilsx.DetectChannelType(ev.Channel)
 	fromType = channelType.String()
 	switch channelType {
-	case slackutilsx.CTypeChannel:
-		info, err := api.GetConversationInfoContext(ctx, ev.Channel, false)
+	case slackutilsx.CTypeChannel, slackutilsx.CTypeGroup, slackutilsx.CTypeDM:
+		info, err := GetDirectory(api).GetConversationInfo(ctx, ev.Channel)
 		if err != nil {
-			if err.Error() == ErrMethodNotSupportedForChannelType {
+			if channelType == slackutilsx.CTypeChannel && err.Error() == ErrMethodNotSupportedForChannelType {
 				// This error occurred by the private channels only converted from the public channel.
 				// So, this is private channel if this error.
 				name, err := ConvertDisplayPrivateChannel(ctx, api, ev.Channel)
@@ -108,35 +115,78 @@ func ConvertDisplayChannelName(ctx context.Context, api *slack.Client, ev *slack
 
 				return fromType, name, nil
 			}
+			if channelType == slackutilsx.CTypeDM && ev.Msg.SubType == "" && ev.Msg.User != "" {
+				// can't read info of DM, so use name of sender
+				info, err := GetDirectory(api).GetUserInfo(ctx, ev.Msg.User)
+				if err != nil {
+					return "", "", err
+				}
+				return fromType, info.Name, nil
+			}
 			return "", "", err
 		}
 
-		return fromType, info.Name, nil
+		return ConvertDisplayConversation(ctx, api, info)
+	}
+
+	return "", "", fmt.Errorf("channel not found")
+}
 
-	case slackutilsx.CTypeGroup:
-		info, err := api.GetConversationInfoContext(ctx, ev.Channel, false)
+// ConvertDisplayConversation retrieve channel type and name from info of conversation.
+// name of DM and MPIM is names of participants (e.g. "alice,bob").
+func ConvertDisplayConversation(ctx context.Context, api *slack.Client, info *slack.Channel) (fromType, name string, err error) {
+	switch {
+	case info.IsIM:
+		self, err := GetDirectory(api).GetSelf(ctx)
 		if err != nil {
 			return "", "", err
 		}
+		if info.User == "" {
+			// empty id is regarded as sender of event by ConvertDisplayUserName
+			return "", "", fmt.Errorf("user of DM is unknown (channel: %s)", info.ID)
+		}
+		other, _, err := ConvertDisplayUserName(ctx, api, nil, info.User)
+		if err != nil {
+			return "", "", err
+		}
+		participants := []string{self.User, other}
+		sort.Strings(participants)
+		return slackutilsx.CTypeDM.String(), strings.Join(participants, ","), nil
+	case info.IsMpIM || strings.HasPrefix(info.Name, "mpdm-"):
+		return ChannelTypeMPIM, ConvertMPIMName(info.Name), nil
+	default:
+		return slackutilsx.DetectChannelType(info.ID).String(), info.Name, nil
+	}
+}
 
-		return fromType, info.Name, nil
-
-	case slackutilsx.CTypeDM:
-		if ev.Msg.SubType != "" {
-			// SubType is not define user
-		} else {
-			info, err := api.GetUserInfoContext(ctx, ev.Msg.User)
-			if err != nil {
-				return "", "", err
-			}
+// ConvertMPIMName convert name of MPIM to names of participants (e.g. "mpdm-alice--bob--carol-1" to "alice,bob,carol")
+func ConvertMPIMName(name string) string {
+	name = strings.TrimPrefix(name, "mpdm-")
+	if i := strings.LastIndex(name, "-"); i >= 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	return strings.Join(strings.Split(name, "--"), ",")
+}
 
-			return fromType, info.Name, nil
+// ConvertChannelKind convert channel type and name to kind of channel
+func ConvertChannelKind(fromType, name string) string {
+	switch fromType {
+	case slackutilsx.CTypeChannel.String():
+		return config.ChannelKindPublic
+	case slackutilsx.CTypeGroup.String():
+		if strings.HasPrefix(name, "mpdm-") {
+			return config.ChannelKindMPIM
 		}
+		return config.ChannelKindPrivate
+	case slackutilsx.CTypeDM.String():
+		return config.ChannelKindDM
+	case ChannelTypeMPIM:
+		return config.ChannelKindMPIM
 	default:
-		name = ""
+		return ""
 	}
-
-	return "", "", fmt.Errorf("channel not found")
 }
 
 // ConvertDisplayUserName retrieve user type and name
@@ -145,7 +195,7 @@ func ConvertDisplayUserName(ctx context.Context, api *slack.Client, ev *slack.Me
 
 	if id != "" {
 		// specific id (maybe user)
-		info, err := api.GetUserInfoContext(ctx, id)
+		info, err := GetDirectory(api).GetUserInfo(ctx, id)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to get user info (user: %s): %w", id, err)
 		}
@@ -154,12 +204,15 @@ func ConvertDisplayUserName(ctx context.Context, api *slack.Client, ev *slack.Me
 	}
 
 	// return self id
+	if ev == nil {
+		return "", "", fmt.Errorf("user id or event is required")
+	}
 	if ev.Msg.BotID == "B01" {
 		// this is slackbot
 		return "Slack bot", "bot", nil
 	} else if ev.Msg.BotID != "" {
 		// this is bot
-		byInfo, err := api.GetBotInfoContext(ctx, ev.Msg.BotID)
+		byInfo, err := GetDirectory(api).GetBotInfo(ctx, ev.Msg.BotID)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to get bot info (bot: %s): %w", ev.Msg.BotID, err)
 		}
@@ -170,7 +223,7 @@ func ConvertDisplayUserName(ctx context.Context, api *slack.Client, ev *slack.Me
 		return ev.Msg.SubType, "status", nil
 	} else {
 		// user
-		byInfo, err := api.GetUserInfoContext(ctx, ev.Msg.User)
+		byInfo, err := GetDirectory(api).GetUserInfo(ctx, ev.Msg.User)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to get user info (user: %s): %w", ev.Msg.User, err)
 		}
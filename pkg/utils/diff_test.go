@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	long := strings.Repeat("a\n", maxDiffLines) + "a"
+
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    string
+	}{
+		{name: "same", oldText: "hello", newText: "hello", want: "  hello"},
+		{name: "replaced", oldText: "hello", newText: "world", want: "- hello\n+ world"},
+		{name: "added", oldText: "a\nc", newText: "a\nb\nc", want: "  a\n+ b\n  c"},
+		{name: "removed", oldText: "a\nb\nc", newText: "a\nc", want: "  a\n- b\n  c"},
+		{name: "appended", oldText: "a", newText: "a\nb", want: "  a\n+ b"},
+		{name: "changed in middle", oldText: "a\nb\nc", newText: "a\nx\nc", want: "  a\n- b\n+ x\n  c"},
+		{name: "from empty", oldText: "", newText: "a", want: "- \n+ a"},
+		{name: "too long", oldText: long, newText: "b", want: "- " + strings.Repeat("a\n- ", maxDiffLines) + "a\n+ b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LineDiff(tt.oldText, tt.newText); got != tt.want {
+				t.Errorf("want:\n%s\nbut:\n%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackutilsx"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/store"
+)
+
+const (
+	// DefaultDirectoryTTL is default TTL of cached channels, users and bots
+	DefaultDirectoryTTL = 10 * time.Minute
+)
+
+var (
+	directories   = map[*slack.Client]*Directory{}
+	directoriesMu sync.Mutex
+)
+
+// Directory is cache of channels, users and bots per workspace
+type Directory struct {
+	api *slack.Client
+
+	mu            sync.RWMutex
+	channelList   []slack.Channel // channels and groups
+	channelListAt time.Time
+	userList      []slack.User
+	userListAt    time.Time
+	groupList     []slack.UserGroup
+	groupListAt   time.Time
+	channels      map[string]cached // key: channel id, value: *slack.Channel
+	users         map[string]cached // key: user id, value: *slack.User
+	bots          map[string]cached // key: bot id, value: *slack.Bot
+	self          *slack.AuthTestResponse
+}
+
+type cached struct {
+	value    interface{}
+	cachedAt time.Time
+}
+
+// GetDirectory get Directory of api
+func GetDirectory(api *slack.Client) *Directory {
+	directoriesMu.Lock()
+	defer directoriesMu.Unlock()
+
+	d, ok := directories[api]
+	if !ok {
+		d = &Directory{
+			api:      api,
+			channels: map[string]cached{},
+			users:    map[string]cached{},
+			bots:     map[string]cached{},
+		}
+		directories[api] = d
+	}
+	return d
+}
+
+// PruneDirectories remove Directory of api that is not used by current config (e.g. token is rotated)
+func PruneDirectories() {
+	current := map[*slack.Client]bool{}
+	for _, api := range store.GetSlackAPIInstances() {
+		current[api] = true
+	}
+
+	directoriesMu.Lock()
+	defer directoriesMu.Unlock()
+	for api := range directories {
+		if !current[api] {
+			delete(directories, api)
+		}
+	}
+}
+
+func directoryTTL() time.Duration {
+	if ttl := config.GetConfig().Cache.TTL.Duration; ttl > 0 {
+		return ttl
+	}
+	return DefaultDirectoryTTL
+}
+
+func (d *Directory) load(m map[string]cached, key string) (interface{}, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	c, ok := m[key]
+	if !ok || time.Since(c.cachedAt) > directoryTTL() {
+		return nil, false
+	}
+	return c.value, true
+}
+
+func (d *Directory) store(m map[string]cached, key string, value interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	m[key] = cached{value: value, cachedAt: time.Now()}
+}
+
+// Warm retrieve channels and users to cache, lists are not retrieved while cache is fresh
+func (d *Directory) Warm(ctx context.Context) error {
+	ttl := directoryTTL()
+	d.mu.RLock()
+	fresh := d.channelList != nil && time.Since(d.channelListAt) <= ttl &&
+		d.userList != nil && time.Since(d.userListAt) <= ttl
+	d.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	if _, err := d.GetChannels(ctx); err != nil {
+		return err
+	}
+
+	_, err := d.GetUsers(ctx)
+	return err
+}
+
+// GetUsers get list of users
+func (d *Directory) GetUsers(ctx context.Context) ([]slack.User, error) {
+	d.mu.RLock()
+	list, at := d.userList, d.userListAt
+	d.mu.RUnlock()
+	if list != nil && time.Since(at) <= directoryTTL() {
+		return list, nil
+	}
+
+	list, err := d.api.GetUsersContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		d.store(d.users, list[i].ID, &list[i])
+	}
+
+	d.mu.Lock()
+	d.userList, d.userListAt = list, time.Now()
+	d.mu.Unlock()
+
+	return list, nil
+}
+
+// FindUser find user by email, or name (user name, display name or real name)
+func (d *Directory) FindUser(ctx context.Context, email, name string) (*slack.User, bool, error) {
+	users, err := d.GetUsers(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if email != "" {
+		for i := range users {
+			if strings.EqualFold(users[i].Profile.Email, email) {
+				return &users[i], true, nil
+			}
+		}
+	}
+	if name == "" {
+		return nil, false, nil
+	}
+	for _, match := range []func(u slack.User) string{
+		func(u slack.User) string { return u.Name },
+		func(u slack.User) string { return u.Profile.DisplayName },
+		func(u slack.User) string { return u.RealName },
+	} {
+		for i := range users {
+			if !users[i].Deleted && strings.EqualFold(match(users[i]), name) {
+				return &users[i], true, nil
+			}
+		}
+	}
+
+	return nil, false, nil
+}
+
+// GetChannels get list of channels and groups
+func (d *Directory) GetChannels(ctx context.Context) ([]slack.Channel, error) {
+	d.mu.RLock()
+	list, at := d.channelList, d.channelListAt
+	d.mu.RUnlock()
+	if list != nil && time.Since(at) <= directoryTTL() {
+		return list, nil
+	}
+
+	list, err := GetConversationsList(ctx, d.api, []slackutilsx.ChannelType{slackutilsx.CTypeChannel, slackutilsx.CTypeGroup})
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.channelList, d.channelListAt = list, time.Now()
+	d.mu.Unlock()
+
+	return list, nil
+}
+
+// GetUserGroups get list of user groups
+func (d *Directory) GetUserGroups(ctx context.Context) ([]slack.UserGroup, error) {
+	d.mu.RLock()
+	list, at := d.groupList, d.groupListAt
+	d.mu.RUnlock()
+	if list != nil && time.Since(at) <= directoryTTL() {
+		return list, nil
+	}
+
+	list, err := d.api.GetUserGroupsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.groupList, d.groupListAt = list, time.Now()
+	d.mu.Unlock()
+
+	return list, nil
+}
+
+// GetUserGroupInfo get info of user group
+func (d *Directory) GetUserGroupInfo(ctx context.Context, groupID string) (*slack.UserGroup, error) {
+	groups, err := d.GetUserGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].ID == groupID {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("user group is not found (id: %s)", groupID)
+}
+
+// GetConversationInfo get info of conversation
+func (d *Directory) GetConversationInfo(ctx context.Context, channelID string) (*slack.Channel, error) {
+	if v, ok := d.load(d.channels, channelID); ok {
+		return v.(*slack.Channel), nil
+	}
+
+	info, err := d.api.GetConversationInfoContext(ctx, channelID, false)
+	if err != nil {
+		return nil, err
+	}
+	d.store(d.channels, channelID, info)
+
+	return info, nil
+}
+
+// GetUserInfo get info of user
+func (d *Directory) GetUserInfo(ctx context.Context, userID string) (*slack.User, error) {
+	if v, ok := d.load(d.users, userID); ok {
+		return v.(*slack.User), nil
+	}
+
+	info, err := d.api.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	d.store(d.users, userID, info)
+
+	return info, nil
+}
+
+// GetBotInfo get info of bot
+func (d *Directory) GetBotInfo(ctx context.Context, botID string) (*slack.Bot, error) {
+	if v, ok := d.load(d.bots, botID); ok {
+		return v.(*slack.Bot), nil
+	}
+
+	info, err := d.api.GetBotInfoContext(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	d.store(d.bots, botID, info)
+
+	return info, nil
+}
+
+// GetSelf get identity of token, it is cached while process is running
+func (d *Directory) GetSelf(ctx context.Context) (*slack.AuthTestResponse, error) {
+	d.mu.RLock()
+	self := d.self
+	d.mu.RUnlock()
+	if self != nil {
+		return self, nil
+	}
+
+	self, err := d.api.AuthTestContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to auth test: %w", err)
+	}
+	d.mu.Lock()
+	d.self = self
+	d.mu.Unlock()
+	return self, nil
+}
+
+// InvalidateChannel remove channel from cache
+func (d *Directory) InvalidateChannel(channelID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.channels, channelID)
+	d.channelList = nil
+}
+
+// SetUser update user in cache, and in list of users that is used by FindUser
+func (d *Directory) SetUser(user slack.User) {
+	d.store(d.users, user.ID, &user)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.userList == nil {
+		return
+	}
+	// copy list, because list that is returned by GetUsers may be read
+	list := make([]slack.User, 0, len(d.userList)+1)
+	found := false
+	for _, u := range d.userList {
+		if u.ID == user.ID {
+			u = user
+			found = true
+		}
+		list = append(list, u)
+	}
+	if !found {
+		// joined user
+		list = append(list, user)
+	}
+	d.userList = list
+}
+
+// SetBot update bot in cache
+func (d *Directory) SetBot(bot slack.Bot) {
+	d.store(d.bots, bot.ID, &bot)
+}
+
+// HandleDirectoryEvent update cache by event, return true if event is handled
+func HandleDirectoryEvent(api *slack.Client, msg slack.RTMEvent) bool {
+	d := GetDirectory(api)
+
+	switch ev := msg.Data.(type) {
+	case *slack.ChannelCreatedEvent:
+		d.InvalidateChannel(ev.Channel.ID)
+	case *slack.GroupCreatedEvent:
+		d.InvalidateChannel(ev.Channel.ID)
+	case *slack.ChannelRenameEvent:
+		d.InvalidateChannel(ev.Channel.ID)
+	case *slack.GroupRenameEvent:
+		d.InvalidateChannel(ev.Group.ID)
+	case *slack.ChannelDeletedEvent:
+		d.InvalidateChannel(ev.Channel)
+	case *slack.ChannelArchiveEvent:
+		d.InvalidateChannel(ev.Channel)
+	case *slack.ChannelUnarchiveEvent:
+		d.InvalidateChannel(ev.Channel)
+	case *slack.GroupArchiveEvent:
+		d.InvalidateChannel(ev.Channel)
+	case *slack.GroupUnarchiveEvent:
+		d.InvalidateChannel(ev.Channel)
+	case *slack.UserChangeEvent:
+		d.SetUser(ev.User)
+	case *slack.TeamJoinEvent:
+		d.SetUser(ev.User)
+	case *slack.BotAddedEvent:
+		d.SetBot(ev.Bot)
+	case *slack.BotChangedEvent:
+		d.SetBot(ev.Bot)
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestDirectorySetUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      slack.User
+		findEmail string
+		findName  string
+		wantID    string
+	}{
+		{
+			name:     "renamed user",
+			user:     slack.User{ID: "U01", Name: "alice2"},
+			findName: "alice2",
+			wantID:   "U01",
+		},
+		{
+			name:     "old name is not found",
+			user:     slack.User{ID: "U01", Name: "alice2"},
+			findName: "alice",
+			wantID:   "",
+		},
+		{
+			name:      "changed email",
+			user:      slack.User{ID: "U02", Name: "bob", Profile: slack.UserProfile{Email: "bob@example.com"}},
+			findEmail: "bob@example.com",
+			wantID:    "U02",
+		},
+		{
+			name:     "joined user",
+			user:     slack.User{ID: "U03", Name: "carol"},
+			findName: "carol",
+			wantID:   "U03",
+		},
+		{
+			name:     "deleted user",
+			user:     slack.User{ID: "U02", Name: "bob", Deleted: true},
+			findName: "bob",
+			wantID:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, api := newTestSourceSlack(t)
+			d := GetDirectory(api)
+			if _, err := d.GetUsers(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			d.SetUser(tt.user)
+
+			u, ok, err := d.FindUser(context.Background(), tt.findEmail, tt.findName)
+			if err != nil {
+				t.Fatalf("failed to find user: %+v", err)
+			}
+			var got string
+			if ok {
+				got = u.ID
+			}
+			if got != tt.wantID {
+				t.Errorf("want %q, but %q", tt.wantID, got)
+			}
+
+			// cached list is updated without retrieving list again
+			n := 0
+			for _, req := range f.requests {
+				if req == "users.list " {
+					n++
+				}
+			}
+			if n != 1 {
+				t.Errorf("list of users is retrieved %d times", n)
+			}
+		})
+	}
+}
+
+func TestDirectorySetUserWithoutList(t *testing.T) {
+	_, api := newTestSourceSlack(t)
+	d := GetDirectory(api)
+
+	// list is not retrieved yet, so it is retrieved by FindUser
+	d.SetUser(slack.User{ID: "U03", Name: "carol"})
+	if _, ok, err := d.FindUser(context.Background(), "", "alice"); err != nil || !ok {
+		t.Errorf("user in list is not found: %v, %+v", ok, err)
+	}
+	if u, err := d.GetUserInfo(context.Background(), "U03"); err != nil || u.Name != "carol" {
+		t.Errorf("set user is not cached: %+v, %+v", u, err)
+	}
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeSlack is fake of Slack API that return users, channels and user groups of workspace
+type fakeSlack struct {
+	users    map[string]map[string]interface{} // key: user id
+	channels map[string]map[string]interface{} // key: channel id
+	groups   []map[string]interface{}
+	history  []map[string]interface{} // messages of conversations.history, newest first
+
+	mu       sync.Mutex
+	requests []string // method and id of requests (e.g. "users.info U01")
+}
+
+// newFakeSlackClient start fake Slack API, and return client of it
+func newFakeSlackClient(t *testing.T, f *fakeSlack) *slack.Client {
+	t.Helper()
+
+	reply := func(w http.ResponseWriter, v map[string]interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	record := func(method, id string) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, method+" "+id)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users.info", func(w http.ResponseWriter, r *http.Request) {
+		id := r.FormValue("user")
+		record("users.info", id)
+		if u, ok := f.users[id]; ok {
+			reply(w, map[string]interface{}{"ok": true, "user": u})
+			return
+		}
+		reply(w, map[string]interface{}{"ok": false, "error": "user_not_found"})
+	})
+	mux.HandleFunc("/users.list", func(w http.ResponseWriter, r *http.Request) {
+		record("users.list", "")
+		var members []map[string]interface{}
+		for _, u := range f.users {
+			members = append(members, u)
+		}
+		reply(w, map[string]interface{}{"ok": true, "members": members})
+	})
+	mux.HandleFunc("/conversations.info", func(w http.ResponseWriter, r *http.Request) {
+		id := r.FormValue("channel")
+		record("conversations.info", id)
+		if c, ok := f.channels[id]; ok {
+			reply(w, map[string]interface{}{"ok": true, "channel": c})
+			return
+		}
+		reply(w, map[string]interface{}{"ok": false, "error": "channel_not_found"})
+	})
+	mux.HandleFunc("/usergroups.list", func(w http.ResponseWriter, r *http.Request) {
+		record("usergroups.list", "")
+		reply(w, map[string]interface{}{"ok": true, "usergroups": f.groups})
+	})
+	mux.HandleFunc("/conversations.history", func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.FormValue("cursor")
+		record("conversations.history", cursor)
+		// cursor is index of first message in page
+		start, _ := strconv.Atoi(cursor)
+		limit, _ := strconv.Atoi(r.FormValue("limit"))
+		if limit <= 0 || start+limit > len(f.history) {
+			limit = len(f.history) - start
+		}
+		end := start + limit
+		resp := map[string]interface{}{"ok": true, "messages": f.history[start:end], "has_more": end < len(f.history)}
+		if end < len(f.history) {
+			resp["response_metadata"] = map[string]interface{}{"next_cursor": strconv.Itoa(end)}
+		}
+		reply(w, resp)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return slack.New("xoxp-fake", slack.OptionAPIURL(server.URL+"/"))
+}
+
+// newTestSourceSlack return client of fake source slack that has alice, #general and @devs
+func newTestSourceSlack(t *testing.T) (*fakeSlack, *slack.Client) {
+	t.Helper()
+
+	f := &fakeSlack{
+		users: map[string]map[string]interface{}{
+			"U01": {"id": "U01", "name": "alice", "profile": map[string]interface{}{"email": "alice@example.com"}},
+			"U02": {"id": "U02", "name": "bob", "real_name": "Bob Smith", "profile": map[string]interface{}{"display_name": "bobby"}},
+		},
+		channels: map[string]map[string]interface{}{
+			"C01": {"id": "C01", "name": "general", "is_channel": true},
+		},
+		groups: []map[string]interface{}{
+			{"id": "S01", "handle": "devs", "name": "Developers"},
+		},
+	}
+	return f, newFakeSlackClient(t, f)
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/outbound"
+	"github.com/whywaita/aguri/pkg/store"
+)
+
+const (
+	// DefaultMaxFileSize is default max size of file that uploaded to aggregated slack
+	DefaultMaxFileSize = 10 * 1024 * 1024
+)
+
+func maxFileSize() int {
+	if size := config.GetTo().MaxFileSize; size > 0 {
+		return size
+	}
+	return DefaultMaxFileSize
+}
+
+// mirrorFiles upload files of source message to aggregated channel, register mapping of files,
+// and return timestamps of aggregated messages that share files
+func mirrorFiles(ctx context.Context, toAPI, fromAPI *slack.Client, ev *slack.MessageEvent, workspace string, param slack.PostMessageParameters, aggrChannelID, threadTimestamp string) ([]string, error) {
+	var posted []string
+	for _, f := range ev.Files {
+		if _, err := store.GetFileMapping(workspace, f.ID, aggrChannelID); err == nil {
+			// already mirrored (e.g. shared again)
+			continue
+		}
+
+		m, err := mirrorFile(ctx, toAPI, fromAPI, f, param, aggrChannelID, threadTimestamp)
+		if err != nil {
+			return posted, err
+		}
+		if m.Timestamp != "" {
+			posted = append(posted, m.Timestamp)
+		}
+		m.SourceTimestamp = ev.Timestamp
+		if err := store.SetFileMapping(workspace, f.ID, *m); err != nil {
+			return posted, err
+		}
+	}
+
+	return posted, nil
+}
+
+func mirrorFile(ctx context.Context, toAPI, fromAPI *slack.Client, f slack.File, param slack.PostMessageParameters, aggrChannelID, threadTimestamp string) (*store.FileMapping, error) {
+	if f.URLPrivateDownload == "" || f.IsExternal || f.Size > maxFileSize() {
+		return postFileLink(ctx, toAPI, f, param, aggrChannelID, threadTimestamp)
+	}
+
+	var buf bytes.Buffer
+	if err := fromAPI.GetFile(f.URLPrivateDownload, &buf); err != nil {
+		// source file may be deleted or restricted, permalink is better than nothing
+		logrus.Infof("failed to download file (id: %s), post link instead: %+v", f.ID, err)
+		return postFileLink(ctx, toAPI, f, param, aggrChannelID, threadTimestamp)
+	}
+
+	var uploaded *slack.File
+	err := outbound.Default.Do(ctx, aggrChannelID, func(ctx context.Context) error {
+		var err error
+		uploaded, err = toAPI.UploadFileContext(ctx, slack.FileUploadParameters{
+			Reader:          bytes.NewReader(buf.Bytes()),
+			Filename:        f.Name,
+			Filetype:        f.Filetype,
+			Title:           f.Title,
+			InitialComment:  "shared by " + param.Username,
+			Channels:        []string{aggrChannelID},
+			ThreadTimestamp: threadTimestamp,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, deliveryError(fmt.Errorf("failed to upload file (id: %s): %w", f.ID, err))
+	}
+
+	return &store.FileMapping{FileID: uploaded.ID, ChannelID: aggrChannelID, Timestamp: sharedTimestamp(uploaded, aggrChannelID)}, nil
+}
+
+// sharedTimestamp return timestamp of message that share uploaded file in channel
+func sharedTimestamp(f *slack.File, channelID string) string {
+	for _, shares := range []map[string][]slack.ShareFileInfo{f.Shares.Public, f.Shares.Private} {
+		if s := shares[channelID]; len(s) != 0 {
+			return s[0].Ts
+		}
+	}
+	return ""
+}
+
+// postFileLink post permalink of file that can't be uploaded
+func postFileLink(ctx context.Context, toAPI *slack.Client, f slack.File, param slack.PostMessageParameters, aggrChannelID, threadTimestamp string) (*store.FileMapping, error) {
+	title := f.Title
+	if title == "" {
+		title = f.Name
+	}
+	msg := fmt.Sprintf("shared a file: <%s|%s>", f.Permalink, title)
+
+	opts := append([]slack.MsgOption{slack.MsgOptionText(msg, false), slack.MsgOptionPostMessageParameters(param)}, threadOptions(threadTimestamp, false)...)
+	_, ts, err := PostMessageQueued(ctx, toAPI, aggrChannelID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post link of file: %w", err)
+	}
+
+	return &store.FileMapping{ChannelID: aggrChannelID, Timestamp: ts}, nil
+}
+
+// DeleteMirroredFile delete files or permalink messages in aggregated slack that mirrored from source file
+func DeleteMirroredFile(ctx context.Context, toAPI *slack.Client, workspace, fileID string) error {
+	mappings, err := store.ListFileMappings(workspace, fileID)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mappings {
+		if m.FileID != "" {
+			err = outbound.Default.Do(ctx, m.ChannelID, func(ctx context.Context) error {
+				if err := toAPI.DeleteFileContext(ctx, m.FileID); err != nil && err.Error() != "file_deleted" && err.Error() != "file_not_found" {
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				return deliveryError(fmt.Errorf("failed to delete file (id: %s): %w", m.FileID, err))
+			}
+		} else if m.Timestamp != "" {
+			if err := DeleteMessageQueued(ctx, toAPI, m.ChannelID, m.Timestamp); err != nil {
+				return fmt.Errorf("failed to delete link of file: %w", err)
+			}
+		}
+
+		if err := store.DeleteFileMapping(workspace, fileID, m.ChannelID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
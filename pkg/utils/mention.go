@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	// reDestUserMention match mention of user in aggregated slack (e.g. "<@U0123>")
+	reDestUserMention = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|[^>]*)?>`)
+	// reDestChannelMention match mention of channel in aggregated slack (e.g. "<#C0123|general>")
+	reDestChannelMention = regexp.MustCompile(`<#([CG][A-Z0-9]+)(?:\|([^>]*))?>`)
+	// reWorkspaceMention match mention that specify workspace (e.g. "@alice@team1" or "@alice (team1)" that generated by RewriteMrkdwn)
+	reWorkspaceMention = regexp.MustCompile(`(^|\s)@([^\s@()<>]+)(?:@([^\s@()<>]+)| \(([^\s()<>]+)\))`)
+)
+
+// RewriteMentionsToSource rewrite mentions in text of aggregated slack to mentions of source slack.
+// users are matched by email or name, user that is not found in source slack is rewritten to plain text.
+func RewriteMentionsToSource(ctx context.Context, toAPI, fromAPI *slack.Client, workspace, text string) string {
+	// rewrite mentions of aggregated slack first, rewritten mentions are not matched by reWorkspaceMention
+	text = reDestUserMention.ReplaceAllStringFunc(text, func(s string) string {
+		id := reDestUserMention.FindStringSubmatch(s)[1]
+		destUser, err := GetDirectory(toAPI).GetUserInfo(ctx, id)
+		if err != nil {
+			return "@" + id
+		}
+		u, ok, err := GetDirectory(fromAPI).FindUser(ctx, destUser.Profile.Email, destUser.Name)
+		if err != nil || !ok {
+			return "@" + destUser.Name
+		}
+		return fmt.Sprintf("<@%s>", u.ID)
+	})
+
+	text = reWorkspaceMention.ReplaceAllStringFunc(text, func(s string) string {
+		m := reWorkspaceMention.FindStringSubmatch(s)
+		prefix, name, ws := m[1], m[2], m[3]
+		if ws == "" {
+			ws = m[4]
+		}
+		if ws != workspace {
+			// user of other workspace can't be mentioned
+			return s
+		}
+		u, ok, err := GetDirectory(fromAPI).FindUser(ctx, "", name)
+		if err != nil || !ok {
+			return prefix + "@" + name
+		}
+		return prefix + fmt.Sprintf("<@%s>", u.ID)
+	})
+
+	return reDestChannelMention.ReplaceAllStringFunc(text, func(s string) string {
+		m := reDestChannelMention.FindStringSubmatch(s)
+		if m[2] != "" {
+			return "#" + m[2]
+		}
+		info, err := GetDirectory(toAPI).GetConversationInfo(ctx, m[1])
+		if err != nil {
+			return "#" + m[1]
+		}
+		return "#" + info.Name
+	})
+}
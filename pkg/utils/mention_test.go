@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRewriteMentionsToSource(t *testing.T) {
+	_, fromAPI := newTestSourceSlack(t)
+	toAPI := newFakeSlackClient(t, &fakeSlack{
+		users: map[string]map[string]interface{}{
+			"UA1": {"id": "UA1", "name": "alice.aggr", "profile": map[string]interface{}{"email": "Alice@example.com"}},
+			"UA2": {"id": "UA2", "name": "bobby"},
+			"UA3": {"id": "UA3", "name": "carol"},
+		},
+		channels: map[string]map[string]interface{}{
+			"CA1": {"id": "CA1", "name": "aggr-team1", "is_channel": true},
+		},
+	})
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain text", text: "hello", want: "hello"},
+		{name: "user of aggregated slack matched by email", text: "<@UA1> hi", want: "<@U01> hi"},
+		{name: "user of aggregated slack matched by name", text: "<@UA2|bobby>", want: "<@U02>"},
+		{name: "user of aggregated slack not in source", text: "<@UA3>", want: "@carol"},
+		{name: "unknown user of aggregated slack", text: "<@UA9>", want: "@UA9"},
+		{name: "workspace mention", text: "cc @alice@team1", want: "cc <@U01>"},
+		{name: "workspace mention by real name is not matched", text: "@Bob Smith@team1", want: "@Bob Smith@team1"},
+		{name: "workspace mention by display name", text: "@Bobby@team1 hi", want: "<@U02> hi"},
+		{name: "rewritten mention", text: "@alice (team1) thanks", want: "<@U01> thanks"},
+		{name: "mention of other workspace", text: "@alice (team2) @alice@team2", want: "@alice (team2) @alice@team2"},
+		{name: "unknown user of workspace", text: "@dave@team1", want: "@dave"},
+		{name: "email is not mention", text: "mail to alice@team1", want: "mail to alice@team1"},
+		{name: "channel with label", text: "<#CA1|aggr-team1>", want: "#aggr-team1"},
+		{name: "channel", text: "<#CA1>", want: "#aggr-team1"},
+		{name: "unknown channel", text: "<#CA9>", want: "#CA9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RewriteMentionsToSource(context.Background(), toAPI, fromAPI, "team1", tt.text); got != tt.want {
+				t.Errorf("want %s, but %s", tt.want, got)
+			}
+		})
+	}
+}
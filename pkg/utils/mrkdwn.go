@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+var (
+	// reMrkdwnToken match special token of mrkdwn (e.g. "<@U0123>", "<#C0123|general>", "<!here>", "<https://example.com|label>")
+	reMrkdwnToken = regexp.MustCompile(`<([^<>\s][^<>]*)>`)
+)
+
+// RewriteMrkdwn rewrite mentions in text of source slack to readable text (e.g. "@alice (team1)").
+// special mentions (@here, @channel, @everyone) are defused, so that aggregated slack is not notified.
+func RewriteMrkdwn(ctx context.Context, fromAPI *slack.Client, workspace, text string) string {
+	return reMrkdwnToken.ReplaceAllStringFunc(text, func(token string) string {
+		body := token[1 : len(token)-1]
+		target, label := body, ""
+		if i := strings.Index(body, "|"); i >= 0 {
+			target, label = body[:i], body[i+1:]
+		}
+
+		switch {
+		case strings.HasPrefix(target, "@"):
+			name := strings.TrimPrefix(label, "@")
+			if name == "" {
+				name = userName(ctx, fromAPI, target[1:])
+			}
+			return fmt.Sprintf("@%s (%s)", name, workspace)
+		case strings.HasPrefix(target, "#"):
+			name := label
+			if name == "" {
+				name = channelName(ctx, fromAPI, target[1:])
+			}
+			return fmt.Sprintf("#%s (%s)", name, workspace)
+		case strings.HasPrefix(target, "!subteam^"):
+			name := strings.TrimPrefix(label, "@")
+			if name == "" {
+				name = userGroupName(ctx, fromAPI, strings.TrimPrefix(target, "!subteam^"))
+			}
+			return fmt.Sprintf("@%s (%s)", name, workspace)
+		case target == "!here", target == "!channel", target == "!everyone":
+			// defuse special mention
+			return "`@" + target[1:] + "`"
+		case strings.HasPrefix(target, "!date^"):
+			return formatDate(target, label)
+		case strings.HasPrefix(target, "!"):
+			// unknown command
+			return label
+		default:
+			// URL, keep label
+			return token
+		}
+	})
+}
+
+// formatDate format "<!date^timestamp^format^link|fallback>"
+func formatDate(target, fallback string) string {
+	if fallback != "" {
+		return fallback
+	}
+	parts := strings.Split(target, "^")
+	if len(parts) < 2 {
+		return target
+	}
+	sec, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return target
+	}
+	return time.Unix(sec, 0).UTC().Format("2006-01-02 15:04 MST")
+}
+
+func userName(ctx context.Context, api *slack.Client, id string) string {
+	if id == "" {
+		// broken mention (e.g. "<@>"), empty id must not be looked up as sender of event
+		return "unknown"
+	}
+	name, _, err := ConvertDisplayUserName(ctx, api, nil, id)
+	if err != nil {
+		return id
+	}
+	return name
+}
+
+func channelName(ctx context.Context, api *slack.Client, id string) string {
+	info, err := GetDirectory(api).GetConversationInfo(ctx, id)
+	if err != nil {
+		return id
+	}
+	return info.Name
+}
+
+func userGroupName(ctx context.Context, api *slack.Client, id string) string {
+	group, err := GetDirectory(api).GetUserGroupInfo(ctx, id)
+	if err != nil {
+		return id
+	}
+	return group.Handle
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRewriteMrkdwn(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain text", text: "hello, a < b > c", want: "hello, a < b > c"},
+		{name: "user", text: "<@U01> hi", want: "@alice (team1) hi"},
+		{name: "user with label", text: "<@U01|ally>", want: "@ally (team1)"},
+		{name: "unknown user", text: "<@U99>", want: "@U99 (team1)"},
+		{name: "empty user", text: "<@>", want: "@unknown (team1)"},
+		{name: "empty user with empty label", text: "<@|>", want: "@unknown (team1)"},
+		{name: "channel", text: "<#C01>", want: "#general (team1)"},
+		{name: "channel with label", text: "<#C01|lobby>", want: "#lobby (team1)"},
+		{name: "unknown channel", text: "<#C99>", want: "#C99 (team1)"},
+		{name: "user group", text: "<!subteam^S01>", want: "@devs (team1)"},
+		{name: "user group with label", text: "<!subteam^S01|@ops>", want: "@ops (team1)"},
+		{name: "unknown user group", text: "<!subteam^S99>", want: "@S99 (team1)"},
+		{name: "here", text: "<!here> deploy", want: "`@here` deploy"},
+		{name: "channel mention with label", text: "<!channel|@channel>", want: "`@channel`"},
+		{name: "everyone", text: "<!everyone>", want: "`@everyone`"},
+		{name: "date with fallback", text: "<!date^1600000000^{date_short}|Sep 13>", want: "Sep 13"},
+		{name: "date without fallback", text: "<!date^1600000000^{date_short}>", want: "2020-09-13 12:26 UTC"},
+		{name: "invalid date", text: "<!date^abc^{date_short}>", want: "!date^abc^{date_short}"},
+		{name: "unknown command", text: "<!foo|bar>", want: "bar"},
+		{name: "link", text: "<https://example.com|example>", want: "<https://example.com|example>"},
+		{name: "mixed", text: "<@U01> in <#C01|general>: <!here>", want: "@alice (team1) in #general (team1): `@here`"},
+	}
+
+	f, api := newTestSourceSlack(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RewriteMrkdwn(context.Background(), api, "team1", tt.text); got != tt.want {
+				t.Errorf("want %s, but %s", tt.want, got)
+			}
+		})
+	}
+
+	// regression: empty user must not be looked up
+	for _, req := range f.requests {
+		if req == "users.info " {
+			t.Errorf("user info of empty id is requested")
+		}
+	}
+}
+
+func TestConvertDisplayUserNameWithoutEvent(t *testing.T) {
+	_, api := newTestSourceSlack(t)
+
+	// regression: empty id without event must not panic
+	if _, _, err := ConvertDisplayUserName(context.Background(), api, nil, ""); err == nil {
+		t.Errorf("error must be returned if id and event are empty")
+	}
+	name, _, err := ConvertDisplayUserName(context.Background(), api, nil, "U01")
+	if err != nil || name != "alice" {
+		t.Errorf("unexpected user name: %s, %v", name, err)
+	}
+}
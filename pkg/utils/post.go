@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/outbound"
+)
+
+// DeliveryError is error of posting to aggregated slack, that event should be delivered again later
+type DeliveryError struct {
+	Err error
+}
+
+func (e *DeliveryError) Error() string {
+	return "failed to deliver to aggregated slack: " + e.Err.Error()
+}
+
+// Unwrap return original error
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// IsDeliveryError check err is caused by aggregated slack
+func IsDeliveryError(err error) bool {
+	var de *DeliveryError
+	return errors.As(err, &de)
+}
+
+// deliveryError wrap err by DeliveryError if it is temporary (e.g. rate limit, server error or network error),
+// so that event is delivered again later. error responses of Slack API (e.g. "channel_not_found") are not retried.
+func deliveryError(err error) error {
+	if outbound.IsRetryable(err) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &DeliveryError{Err: err}
+	}
+	return err
+}
+
+// PostMessageQueued post message via outbound queue of channel.
+// channelID must be id, not name, so that one channel has one queue.
+func PostMessageQueued(ctx context.Context, api *slack.Client, channelID string, options ...slack.MsgOption) (respChannel, respTimestamp string, err error) {
+	err = outbound.Default.Do(ctx, channelID, func(ctx context.Context) error {
+		var err error
+		respChannel, respTimestamp, err = api.PostMessageContext(ctx, channelID, options...)
+		return err
+	})
+	if err != nil {
+		return "", "", deliveryError(err)
+	}
+	return respChannel, respTimestamp, nil
+}
+
+// UpdateMessageQueued update message via outbound queue of channel
+func UpdateMessageQueued(ctx context.Context, api *slack.Client, channelID, timestamp string, options ...slack.MsgOption) error {
+	err := outbound.Default.Do(ctx, channelID, func(ctx context.Context) error {
+		_, _, _, err := api.UpdateMessageContext(ctx, channelID, timestamp, options...)
+		return err
+	})
+	if err != nil {
+		return deliveryError(err)
+	}
+	return nil
+}
+
+// PostSystemMessageInThread post message of aguri to thread of aggregated message, and return timestamp of it
+func PostSystemMessageInThread(ctx context.Context, api *slack.Client, channelID, threadTimestamp, msg string) (string, error) {
+	param := slack.PostMessageParameters{
+		Username:  "aguri@s:system",
+		IconEmoji: ":ghost:",
+	}
+	_, ts, err := PostMessageQueued(ctx, api, channelID,
+		slack.MsgOptionText(msg, false),
+		slack.MsgOptionPostMessageParameters(param),
+		slack.MsgOptionTS(threadTimestamp),
+	)
+	return ts, err
+}
+
+// DeleteMessageQueued delete message via outbound queue of channel, message that is already deleted is ignored
+func DeleteMessageQueued(ctx context.Context, api *slack.Client, channelID, timestamp string) error {
+	err := outbound.Default.Do(ctx, channelID, func(ctx context.Context) error {
+		_, _, err := api.DeleteMessageContext(ctx, channelID, timestamp)
+		if err != nil && err.Error() == "message_not_found" {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return deliveryError(err)
+	}
+	return nil
+}
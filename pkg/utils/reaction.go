@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/outbound"
+)
+
+var (
+	// ErrEmojiNotFound is error message for emoji is not exist in workspace
+	ErrEmojiNotFound = fmt.Errorf("emoji is not found")
+)
+
+// AddReactionQueued add reaction via outbound queue of channel, reaction that is already added is ignored
+func AddReactionQueued(ctx context.Context, api *slack.Client, channelID, timestamp, name string) error {
+	var notFound bool
+	err := outbound.Default.Do(ctx, channelID, func(ctx context.Context) error {
+		err := api.AddReactionContext(ctx, name, slack.NewRefToMessage(channelID, timestamp))
+		if err == nil {
+			return nil
+		}
+		switch err.Error() {
+		case "already_reacted":
+			return nil
+		case "invalid_name":
+			// custom emoji of other workspace
+			notFound = true
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return deliveryError(err)
+	}
+	if notFound {
+		return ErrEmojiNotFound
+	}
+	return nil
+}
+
+// RemoveReactionQueued remove reaction via outbound queue of channel, reaction that is not added is ignored
+func RemoveReactionQueued(ctx context.Context, api *slack.Client, channelID, timestamp, name string) error {
+	err := outbound.Default.Do(ctx, channelID, func(ctx context.Context) error {
+		err := api.RemoveReactionContext(ctx, name, slack.NewRefToMessage(channelID, timestamp))
+		if err != nil {
+			switch err.Error() {
+			case "no_reaction", "invalid_name", "message_not_found":
+				return nil
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return deliveryError(err)
+	}
+	return nil
+}
+
+// HasReactionByOthers check message has reaction by users except exceptUserID
+func HasReactionByOthers(ctx context.Context, api *slack.Client, channelID, timestamp, name, exceptUserID string) (bool, error) {
+	reactions, err := api.GetReactionsContext(ctx, slack.NewRefToMessage(channelID, timestamp), slack.GetReactionsParameters{Full: true})
+	if err != nil {
+		return false, fmt.Errorf("failed to get reactions: %w", err)
+	}
+	for _, r := range reactions {
+		if r.Name != name {
+			continue
+		}
+		for _, u := range r.Users {
+			if u != exceptUserID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+const (
+	// mirroredReactionTTL is period that mirrored reaction is expected to be notified
+	mirroredReactionTTL = time.Minute
+)
+
+var (
+	mirroredReactions   = map[string]time.Time{} // key: workspace, channel, timestamp, name and operation
+	mirroredReactionsMu sync.Mutex
+)
+
+func mirroredReactionKey(workspace, channelID, timestamp, name string, added bool) string {
+	return strings.Join([]string{workspace, channelID, timestamp, name, strconv.FormatBool(added)}, ",")
+}
+
+// MarkMirroredReaction record reaction that aguri mirror to source slack, so that it is not mirrored again
+func MarkMirroredReaction(workspace, channelID, timestamp, name string, added bool) {
+	mirroredReactionsMu.Lock()
+	defer mirroredReactionsMu.Unlock()
+
+	now := time.Now()
+	for k, t := range mirroredReactions {
+		if now.Sub(t) > mirroredReactionTTL {
+			delete(mirroredReactions, k)
+		}
+	}
+	mirroredReactions[mirroredReactionKey(workspace, channelID, timestamp, name, added)] = now
+}
+
+// IsMirroredReaction check reaction is mirrored by aguri, and forget it
+func IsMirroredReaction(workspace, channelID, timestamp, name string, added bool) bool {
+	mirroredReactionsMu.Lock()
+	defer mirroredReactionsMu.Unlock()
+
+	key := mirroredReactionKey(workspace, channelID, timestamp, name, added)
+	t, ok := mirroredReactions[key]
+	if !ok {
+		return false
+	}
+	delete(mirroredReactions, key)
+	return time.Since(t) <= mirroredReactionTTL
+}
+
+// Reaction is added or removed reaction to message
+type Reaction struct {
+	User      string
+	Channel   string
+	Timestamp string
+	Name      string
+	Added     bool
+}
+
+// ParseReactionEvent get Reaction from event, return false if it is not reaction to message
+func ParseReactionEvent(data interface{}) (Reaction, bool) {
+	switch ev := data.(type) {
+	case *slack.ReactionAddedEvent:
+		if ev.Item.Type != "message" {
+			return Reaction{}, false
+		}
+		return Reaction{User: ev.User, Channel: ev.Item.Channel, Timestamp: ev.Item.Timestamp, Name: ev.Reaction, Added: true}, true
+	case *slack.ReactionRemovedEvent:
+		if ev.Item.Type != "message" {
+			return Reaction{}, false
+		}
+		return Reaction{User: ev.User, Channel: ev.Item.Channel, Timestamp: ev.Item.Timestamp, Name: ev.Reaction, Added: false}, true
+	}
+	return Reaction{}, false
+}
@@ -7,21 +7,28 @@ import (
 	"strings"
 
 	"github.com/slack-go/slack"
-	"github.com/slack-go/slack/slackutilsx"
 	"github.com/whywaita/aguri/pkg/config"
 	"github.com/whywaita/aguri/pkg/store"
 )
 
+const (
+	// BackfillUsernameSuffix is suffix of username that mark message is backfilled
+	BackfillUsernameSuffix = " (backfill)"
+
+	// historyPageSize is max number of messages per request of history
+	historyPageSize = 200
+)
+
 var (
-	reUser = regexp.MustCompile(`<@U(\S+)>`)
+	reAguriUsername = regexp.MustCompile(`(\S+)@(\S+):(\S+)`)
 )
 
 // IsExistChannel check exist
 func IsExistChannel(ctx context.Context, api *slack.Client, searchName string) (bool, *slack.Channel, error) {
 	// channel is exist => True
-	channels, err := GetConversationsList(ctx, api, []slackutilsx.ChannelType{slackutilsx.CTypeChannel, slackutilsx.CTypeGroup})
+	channels, err := GetDirectory(api).GetChannels(ctx)
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to get conversation list: %w", err)
+		return false, nil, deliveryError(fmt.Errorf("failed to get conversation list: %w", err))
 	}
 
 	for _, channel := range channels {
@@ -40,34 +47,75 @@ func GetMessageByTS(ctx context.Context, api *slack.Client, channel, timestamp s
 		ChannelID: channel,
 		Latest:    timestamp,
 		Oldest:    timestamp,
+		Inclusive: true,
+		Limit:     1,
 	}
 
 	history, err := api.GetConversationHistoryContext(ctx, historyParam)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message history by timestamp: %w", err)
 	}
+	if len(history.Messages) == 0 {
+		return nil, fmt.Errorf("message is not found (channel: %s, timestamp: %s)", channel, timestamp)
+	}
 
 	msg := history.Messages[0]
 
 	return &msg, nil
 }
 
-// ConvertIDToNameInMsg convert channel name in msg
-func ConvertIDToNameInMsg(ctx context.Context, msg string, ev *slack.MessageEvent, fromAPI *slack.Client) (string, error) {
-	userIds := reUser.FindAllStringSubmatch(ev.Text, -1)
-	if len(userIds) != 0 {
-		for _, ids := range userIds {
-			id := strings.TrimPrefix(ids[0], "<@")
-			id = strings.TrimSuffix(id, ">")
-			name, _, err := ConvertDisplayUserName(ctx, fromAPI, ev, id)
-			if err != nil {
-				return "", err
-			}
-			msg = strings.Replace(msg, id, name, -1)
+// HasReplies check message is parent of thread that has replies
+func HasReplies(ctx context.Context, api *slack.Client, channel, timestamp string) (bool, error) {
+	msg, err := GetMessageByTS(ctx, api, channel, timestamp)
+	if err != nil {
+		return false, err
+	}
+	return msg.ReplyCount > 0, nil
+}
+
+// StrikeThrough format text as strike through per line
+func StrikeThrough(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines[i] = "~" + line + "~"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetMessagesAfter get messages that posted after oldest in channel, sorted by oldest first.
+// messages are paged forward from oldest, so if there are more than limit messages,
+// only oldest limit messages are returned and hasMore is true.
+func GetMessagesAfter(ctx context.Context, api *slack.Client, channel, oldest string, limit int) (messages []slack.Message, hasMore bool, err error) {
+	for len(messages) < limit {
+		n := limit - len(messages)
+		if n > historyPageSize {
+			n = historyPageSize
 		}
+		historyParam := &slack.GetConversationHistoryParameters{
+			ChannelID: channel,
+			Oldest:    oldest,
+			Limit:     n,
+		}
+		history, err := api.GetConversationHistoryContext(ctx, historyParam)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get message history (channel: %s): %w", channel, err)
+		}
+
+		// history.Messages start newest message, and they are messages immediately after oldest
+		page := history.Messages
+		for i := len(page) - 1; i >= 0; i-- {
+			messages = append(messages, page[i])
+		}
+		if !history.HasMore || len(page) == 0 {
+			return messages, false, nil
+		}
+		oldest = page[0].Timestamp
 	}
 
-	return msg, nil
+	return messages, true, nil
 }
 
 // GetUserInfo get info of user
@@ -79,7 +127,7 @@ func GetUserInfo(ctx context.Context, fromAPI *slack.Client, ev *slack.MessageEv
 	}
 
 	if usertype == "user" {
-		u, err := fromAPI.GetUserInfo(ev.Msg.User)
+		u, err := GetDirectory(fromAPI).GetUserInfo(ctx, ev.Msg.User)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to get user info: %w", err)
 		}
@@ -91,12 +139,12 @@ func GetUserInfo(ctx context.Context, fromAPI *slack.Client, ev *slack.MessageEv
 	return user, icon, nil
 }
 
-// PostMessageToChannel port message to aggrChannelName
-func PostMessageToChannel(ctx context.Context, toAPI, fromAPI *slack.Client, ev *slack.MessageEvent, msg, aggrChannelName string) error {
+// PostMessageToChannel port message to aggrChannelName that resolved destination of workspace
+func PostMessageToChannel(ctx context.Context, toAPI, fromAPI *slack.Client, ev *slack.MessageEvent, msg, workspace, aggrChannelName string) error {
 	// post aggregate message
 	var err error
 
-	isExist, _, err := IsExistChannel(ctx, toAPI, aggrChannelName)
+	isExist, aggrCh, err := IsExistChannel(ctx, toAPI, aggrChannelName)
 	if isExist == false {
 		return fmt.Errorf("channel is not found: %w", err)
 	}
@@ -109,45 +157,151 @@ func PostMessageToChannel(ctx context.Context, toAPI, fromAPI *slack.Client, ev
 	if err != nil {
 		return fmt.Errorf("failed to convert channel name: %w", err)
 	}
+	if kind := ConvertChannelKind(fType, position); (kind == config.ChannelKindDM || kind == config.ChannelKindMPIM) && !aggrCh.IsPrivate {
+		// members of aggregated slack must not read direct messages
+		return fmt.Errorf("direct message is not posted to public channel #%s, make it private", aggrChannelName)
+	}
 
 	param := slack.PostMessageParameters{
 		IconURL: icon,
 	}
-	username := user + "@" + strings.ToLower(fType[:1]) + ":" + position
+	displayPosition := position
+	if isSharedDestination(workspace, aggrChannelName) {
+		// shared by some workspaces, so need name of workspace
+		displayPosition = JoinSourceChannel(workspace, position)
+	}
+	username := user + "@" + strings.ToLower(fType[:1]) + ":" + displayPosition
+	if IsBackfill(ctx) {
+		username += BackfillUsernameSuffix
+	}
 	param.Username = username
 
 	attachments := ev.Attachments
 
-	// convert user id to user name in message
-	msg, err = ConvertIDToNameInMsg(ctx, msg, ev, fromAPI)
+	// convert mentions in message to readable text
+	msg = RewriteMrkdwn(ctx, fromAPI, workspace, msg)
+
+	threadTimestamp, broadcast, msg := resolveThread(ctx, toAPI, fromAPI, ev, msg, workspace, aggrCh.ID)
+	threadOpts := threadOptions(threadTimestamp, broadcast)
+
+	// timestamps of aggregated messages, first one is primary
+	var posted []string
+
+	blocks, err := ConvertBlocks(ctx, fromAPI, workspace, ev.Blocks)
 	if err != nil {
-		return fmt.Errorf("failed to convert id to name: %w", err)
+		return fmt.Errorf("failed to convert blocks: %w", err)
+	}
+
+	if len(blocks) != 0 && msg != "" && msg != RewriteMrkdwn(ctx, fromAPI, workspace, ev.Text) {
+		// text is not shown if blocks exist, so show header of message (e.g. quote of parent, original text) by block
+		blocks = prependTextBlock(msg, blocks)
 	}
 
-	workspace := strings.TrimPrefix(aggrChannelName, config.PrefixSlackChannel)
-	if msg != "" {
-		respChannel, respTimestamp, err := toAPI.PostMessageContext(ctx, aggrChannelName, slack.MsgOptionText(msg, false), slack.MsgOptionPostMessageParameters(param))
+	if msg != "" || len(blocks) != 0 {
+		opts := append([]slack.MsgOption{slack.MsgOptionText(msg, false), slack.MsgOptionPostMessageParameters(param)}, threadOpts...)
+		if len(blocks) != 0 {
+			opts = append(opts, slack.MsgOptionBlocks(blocks...))
+		}
+		respChannel, respTimestamp, err := PostMessageQueued(ctx, toAPI, aggrCh.ID, opts...)
 		if err != nil {
 			return fmt.Errorf("failed to post message: %w", err)
 		}
-		store.SetSlackLog(workspace, ev.Timestamp, position, msg, respChannel, respTimestamp)
+		posted = append(posted, respTimestamp)
+		if err := setSlackLogs(workspace, ev, position, msg, respChannel, posted); err != nil {
+			return err
+		}
 	}
 	// if msg is blank, maybe bot_message (for example, twitter integration).
 	// so, must post blank msg if this post have attachments.
 	if attachments != nil {
 		for _, attachment := range attachments {
-			respChannel, respTimestamp, err := toAPI.PostMessageContext(ctx, aggrChannelName, slack.MsgOptionPostMessageParameters(param), slack.MsgOptionAttachments(attachment))
+			opts := append([]slack.MsgOption{slack.MsgOptionPostMessageParameters(param), slack.MsgOptionAttachments(attachment)}, threadOpts...)
+			respChannel, respTimestamp, err := PostMessageQueued(ctx, toAPI, aggrCh.ID, opts...)
 			if err != nil {
-				return fmt.Errorf("failed to post message: %w", err)
+				return partialError(fmt.Errorf("failed to post message: %w", err), posted)
+			}
+			posted = append(posted, respTimestamp)
+			if err := setSlackLogs(workspace, ev, position, msg, respChannel, posted); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(ev.Files) != 0 {
+		shared, err := mirrorFiles(ctx, toAPI, fromAPI, ev, workspace, param, aggrCh.ID, threadTimestamp)
+		if len(posted) == 0 && len(shared) != 0 {
+			// file only message, so message that share first file is primary
+			if err := setSlackLogs(workspace, ev, position, msg, aggrCh.ID, shared[:1]); err != nil {
+				return err
 			}
-			store.SetSlackLog(workspace, ev.Timestamp, position, msg, respChannel, respTimestamp)
+			posted = shared[:1]
+		}
+		if err != nil {
+			return partialError(fmt.Errorf("failed to mirror files: %w", err), posted)
 		}
 	}
 
 	return nil
 }
 
+// partialError make err not to be retried if a part of message is already posted,
+// because retrying post the part again
+func partialError(err error, posted []string) error {
+	if len(posted) == 0 || !IsDeliveryError(err) {
+		return err
+	}
+	return fmt.Errorf("message is partially posted, rest is not retried: %s", err.Error())
+}
+
+// setSlackLogs set LogData keyed by source message, and reverse index keyed by aggregated messages
+func setSlackLogs(workspace string, ev *slack.MessageEvent, channelName, text, toAPIChannelID string, toAPITimestamps []string) error {
+	d := store.LogData{
+		Channel:              channelName,
+		Body:                 text,
+		ToAPIChannelID:       toAPIChannelID,
+		ToAPITimestamp:       toAPITimestamps[0],
+		ToAPIExtraTimestamps: toAPITimestamps[1:],
+		SourceChannelID:      ev.Channel,
+		SourceTimestamp:      ev.Timestamp,
+	}
+	if IsThreadReply(ev) {
+		d.SourceThreadTimestamp = ev.ThreadTimestamp
+	}
+	if err := store.PutSlackLog(workspace, ev.Timestamp, d); err != nil {
+		return err
+	}
+
+	// only newest one is not registered yet
+	d.Workspace = workspace
+	return store.PutReverseSlackLog(toAPIChannelID, toAPITimestamps[len(toAPITimestamps)-1], d)
+}
+
+// JoinSourceChannel join name of workspace and channel (e.g. "team1/general")
+func JoinSourceChannel(workspace, channelName string) string {
+	return workspace + "/" + channelName
+}
+
+// SplitSourceChannel split name that joined by JoinSourceChannel.
+// workspace is empty if name is not joined.
+func SplitSourceChannel(name string) (workspace, channelName string) {
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
 // GenerateAguriUsername generate name that format of aguri
 func GenerateAguriUsername(ch *slack.Channel, displayUsername string) string {
 	return displayUsername + "@" + strings.ToLower(ch.ID[:1]) + ":" + ch.Name
 }
+
+// ParseAguriUsername parse name that format of aguri (e.g. "user@c:channel")
+func ParseAguriUsername(username string) (displayUsername, channelType, channelName string, ok bool) {
+	m := reAguriUsername.FindStringSubmatch(username)
+	if len(m) < 4 {
+		return "", "", "", false
+	}
+
+	return m[1], m[2], m[3], true
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/config"
+	"github.com/whywaita/aguri/pkg/store"
+)
+
+const (
+	// searchWindowSeconds is range of searching aggregated message after source message posted
+	searchWindowSeconds = 300
+	// maxSearchPages is max number of pages of history that searched in window
+	maxSearchPages = 5
+)
+
+var (
+	// ErrAggregatedMessageNotFound is error message for aggregated message is not found in history
+	ErrAggregatedMessageNotFound = fmt.Errorf("aggregated message is not found")
+)
+
+// isMissSlackLog return true if err is a miss of store
+func isMissSlackLog(err error) bool {
+	return errors.Is(err, store.ErrSourceChannelNotFound) || errors.Is(err, store.ErrSlackLogEvicted)
+}
+
+// LookupSourceSlackLog get LogData by timestamp of source message.
+// if store is missed, search aggregated channel history and back-fill store.
+// source is source message before change (e.g. previous_message), it is got from history if nil.
+func LookupSourceSlackLog(ctx context.Context, toAPI, fromAPI *slack.Client, workspace, sourceChannelID, timestamp string, source *slack.Msg) (*store.LogData, error) {
+	d, err := store.GetSlackLog(workspace, timestamp)
+	if err == nil || !isMissSlackLog(err) {
+		return d, err
+	}
+
+	found, ferr := searchSourceSlackLog(ctx, toAPI, fromAPI, workspace, sourceChannelID, timestamp, source)
+	if ferr != nil {
+		// return original error to notice reason of miss
+		return nil, fmt.Errorf("%w (fallback: %s)", err, ferr.Error())
+	}
+	if err := store.PutSlackLog(workspace, timestamp, *found); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// LookupAggregatedSlackLog get LogData by timestamp of aggregated message.
+// Workspace of returned LogData is name of source workspace, workspace of argument is used if it is not known.
+// if store is missed, get aggregated message and parse source channel from username.
+func LookupAggregatedSlackLog(ctx context.Context, toAPI *slack.Client, workspace, aggrChannelID, timestamp string) (*store.LogData, error) {
+	// reverse index that registered when posting
+	d, err := store.GetReverseSlackLog(aggrChannelID, timestamp)
+	if err == nil {
+		return d, nil
+	}
+	if !isMissSlackLog(err) {
+		return nil, err
+	}
+
+	msg, ferr := GetMessageByTS(ctx, toAPI, aggrChannelID, timestamp)
+	if ferr != nil {
+		return nil, fmt.Errorf("%w (fallback: %s)", err, ferr.Error())
+	}
+	_, _, position, ok := ParseAguriUsername(msg.Username)
+	if !ok {
+		return nil, fmt.Errorf("%w (fallback: invalid username: %s)", err, msg.Username)
+	}
+	ws, chName := SplitSourceChannel(position)
+	if ws == "" {
+		ws = workspace
+	}
+	if ws == "" {
+		return nil, fmt.Errorf("%w (fallback: workspace is unknown: %s)", err, msg.Username)
+	}
+
+	found := store.LogData{
+		Workspace:      ws,
+		Channel:        chName,
+		Body:           msg.Text,
+		ToAPIChannelID: aggrChannelID,
+		ToAPITimestamp: timestamp,
+	}
+	if err := store.PutReverseSlackLog(aggrChannelID, timestamp, found); err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+func searchSourceSlackLog(ctx context.Context, toAPI, fromAPI *slack.Client, workspace, sourceChannelID, timestamp string, source *slack.Msg) (*store.LogData, error) {
+	info, err := GetDirectory(fromAPI).GetConversationInfo(ctx, sourceChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source channel info: %w", err)
+	}
+
+	fromType, sourceName, err := ConvertDisplayConversation(ctx, fromAPI, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source channel name: %w", err)
+	}
+
+	toChannelName := config.ResolveDestination(workspace, ConvertChannelKind(fromType, sourceName), sourceName)
+	isExist, aggrCh, err := IsExistChannel(ctx, toAPI, toChannelName)
+	if !isExist {
+		return nil, fmt.Errorf("aggregated channel is not found: %w", err)
+	}
+
+	ts, err := strconv.ParseFloat(timestamp, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	messages, err := getHistoryInWindow(ctx, toAPI, aggrCh.ID, timestamp, strconv.FormatFloat(ts+searchWindowSeconds, 'f', 6, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	if source == nil {
+		if m, err := GetMessageByTS(ctx, fromAPI, sourceChannelID, timestamp); err == nil {
+			// source message is not deleted yet
+			source = &m.Msg
+		}
+	}
+	var username, text string
+	if source != nil {
+		// username is empty if it can't be resolved, then only channel is matched
+		username, _, _ = GetUserInfo(ctx, fromAPI, &slack.MessageEvent{Msg: *source})
+		if source.Edited == nil {
+			// text of edited message is different from aggregated message
+			text = RewriteMrkdwn(ctx, fromAPI, workspace, source.Text)
+		}
+	}
+
+	// messages start newest message, search oldest message that posted from source message
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		if !isAggregatedFrom(m, workspace, sourceName, username, text) {
+			continue
+		}
+		_, _, position, _ := ParseAguriUsername(m.Username)
+		_, chName := SplitSourceChannel(position)
+
+		return &store.LogData{
+			Channel:         chName,
+			Body:            m.Text,
+			ToAPIChannelID:  aggrCh.ID,
+			ToAPITimestamp:  m.Timestamp,
+			SourceChannelID: sourceChannelID,
+			SourceTimestamp: timestamp,
+		}, nil
+	}
+
+	return nil, ErrAggregatedMessageNotFound
+}
+
+// getHistoryInWindow get messages between oldest and latest in channel, sorted by newest first.
+// busy channel has many messages in window, so up to maxSearchPages pages are followed.
+func getHistoryInWindow(ctx context.Context, api *slack.Client, channel, oldest, latest string) ([]slack.Message, error) {
+	var messages []slack.Message
+	var cursor string
+	for page := 0; page < maxSearchPages; page++ {
+		param := &slack.GetConversationHistoryParameters{
+			ChannelID: channel,
+			Oldest:    oldest,
+			Latest:    latest,
+			Inclusive: true,
+			Limit:     historyPageSize,
+			Cursor:    cursor,
+		}
+		resp, err := api.GetConversationHistoryContext(ctx, param)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get history of aggregated channel: %w", err)
+		}
+		messages = append(messages, resp.Messages...)
+
+		cursor = resp.ResponseMetaData.NextCursor
+		if !resp.HasMore || cursor == "" {
+			return messages, nil
+		}
+	}
+
+	return messages, nil
+}
+
+// isAggregatedFrom check aggregated message m is posted from source channel by username with text.
+// username and text are not checked if empty.
+func isAggregatedFrom(m slack.Message, workspace, sourceName, username, text string) bool {
+	_, _, position, ok := ParseAguriUsername(m.Username)
+	if !ok {
+		return false
+	}
+	ws, chName := SplitSourceChannel(position)
+	if chName != sourceName || (ws != "" && ws != workspace) {
+		return false
+	}
+	if username != "" && !strings.HasPrefix(m.Username, username+"@") {
+		return false
+	}
+	if text != "" && !strings.Contains(m.Text, text) {
+		// aggregated message may be prefixed by quote of thread parent
+		return false
+	}
+	return true
+}
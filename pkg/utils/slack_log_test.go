@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestGetHistoryInWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		messages  int
+		wantCount int
+		wantPages int
+	}{
+		{name: "empty", messages: 0, wantCount: 0, wantPages: 1},
+		{name: "one page", messages: historyPageSize, wantCount: historyPageSize, wantPages: 1},
+		{name: "some pages", messages: historyPageSize*2 + 1, wantCount: historyPageSize*2 + 1, wantPages: 3},
+		{name: "too many pages", messages: historyPageSize * (maxSearchPages + 1), wantCount: historyPageSize * maxSearchPages, wantPages: maxSearchPages},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeSlack{}
+			for i := 0; i < tt.messages; i++ {
+				// newest first
+				f.history = append(f.history, map[string]interface{}{"type": "message", "ts": fmt.Sprintf("1600000000.%06d", tt.messages-i)})
+			}
+			api := newFakeSlackClient(t, f)
+
+			messages, err := getHistoryInWindow(context.Background(), api, "CA1", "1600000000.000000", "1600000300.000000")
+			if err != nil {
+				t.Fatalf("failed to get history: %+v", err)
+			}
+			if len(messages) != tt.wantCount {
+				t.Errorf("unexpected number of messages: want %d, but %d", tt.wantCount, len(messages))
+			}
+			for i := 1; i < len(messages); i++ {
+				if messages[i-1].Timestamp <= messages[i].Timestamp {
+					t.Errorf("messages must be sorted by newest first: %s, %s", messages[i-1].Timestamp, messages[i].Timestamp)
+					break
+				}
+			}
+			if len(f.requests) != tt.wantPages {
+				t.Errorf("unexpected number of requests: want %d, but %v", tt.wantPages, f.requests)
+			}
+		})
+	}
+}
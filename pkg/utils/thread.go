@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/whywaita/aguri/pkg/config"
+)
+
+const (
+	// maxQuoteLength is max length of parent message in header of thread reply
+	maxQuoteLength = 100
+)
+
+// IsThreadReply check message is reply in thread, not parent of thread
+func IsThreadReply(ev *slack.MessageEvent) bool {
+	return ev.ThreadTimestamp != "" && ev.ThreadTimestamp != ev.Timestamp
+}
+
+// resolveThread return timestamp of aggregated parent message that reply is posted to.
+// if aggregated parent message is unknown, msg is prefixed by quote of source parent message.
+func resolveThread(ctx context.Context, toAPI, fromAPI *slack.Client, ev *slack.MessageEvent, msg, workspace, aggrChannelID string) (threadTimestamp string, broadcast bool, quoted string) {
+	if !IsThreadReply(ev) {
+		return "", false, msg
+	}
+
+	var parentText string
+	parent, err := LookupSourceSlackLog(ctx, toAPI, fromAPI, workspace, ev.Channel, ev.ThreadTimestamp, nil)
+	if err == nil {
+		if parent.ToAPIChannelID == aggrChannelID && parent.ToAPITimestamp != "" {
+			broadcast = ev.SubType == "thread_broadcast" && config.GetTo().ThreadBroadcast
+			return parent.ToAPITimestamp, broadcast, msg
+		}
+		// parent is posted to other channel (e.g. route is changed)
+		parentText = parent.Body
+	} else if m, err := GetMessageByTS(ctx, fromAPI, ev.Channel, ev.ThreadTimestamp); err == nil {
+		parentText = m.Text
+	}
+
+	return "", false, quoteParent(parentText) + msg
+}
+
+// threadOptions return options to post message into thread
+func threadOptions(threadTimestamp string, broadcast bool) []slack.MsgOption {
+	if threadTimestamp == "" {
+		return nil
+	}
+	opts := []slack.MsgOption{slack.MsgOptionTS(threadTimestamp)}
+	if broadcast {
+		opts = append(opts, slack.MsgOptionBroadcast())
+	}
+	return opts
+}
+
+// quoteParent generate header of thread reply that parent is unknown
+func quoteParent(parentText string) string {
+	line := strings.SplitN(parentText, "\n", 2)[0]
+	if r := []rune(line); len(r) > maxQuoteLength {
+		line = string(r[:maxQuoteLength]) + "..."
+	}
+	if line == "" {
+		return "> (reply in thread)\n"
+	}
+	return "> (reply in thread) " + line + "\n"
+}